@@ -0,0 +1,71 @@
+package advisor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/m-horky/insights-client-next/api"
+)
+
+var service api.Service
+
+// Init has to be called to set up the API configuration for the service.
+func Init(s *api.Service) {
+	service = *s
+	service.Path = "api/insights/v1"
+}
+
+// GetReport returns the list of recommendations for the host.
+//
+// When `etag` is not empty and the report has not changed since, Report.NotModified is set.
+func GetReport(insightsInventoryID, etag string) (*Report, api.IError) {
+	slog.Debug("querying Advisor for a host report", slog.String("etag", etag))
+
+	headers := map[string][]string{}
+	if etag != "" {
+		headers["If-None-Match"] = []string{etag}
+	}
+
+	endpoint := fmt.Sprintf("system/%s/reports/", insightsInventoryID)
+	response, err := service.MakeRequest("GET", endpoint, url.Values{}, headers, nil)
+	if err != nil {
+		slog.Error("could not contact Advisor", slog.String("error", err.Error()))
+		return nil, api.NewError(
+			api.ErrServiceUnreachable,
+			err,
+			nil,
+			"Advisor could not be contacted.",
+		)
+	}
+
+	if response.Code == 304 {
+		slog.Debug("Advisor report has not changed")
+		return &Report{ETag: etag, NotModified: true}, nil
+	}
+
+	if response.Code != 200 {
+		slog.Error("Advisor request failed", slog.String("raw response", string(response.Data)))
+		return nil, api.NewError(
+			api.ErrBadResponse,
+			nil,
+			response,
+			getHumanErrorOnNon200(response.Code),
+		)
+	}
+
+	report := &Report{ETag: response.Header.Get("ETag")}
+	if err := json.Unmarshal(response.Data, &report.Hits); err != nil {
+		slog.Error("could not unmarshal response", slog.String("error", err.Error()))
+		return nil, api.NewError(
+			api.ErrUnparseable,
+			err,
+			response,
+			"Advisor response is malformed.",
+		)
+	}
+
+	slog.Debug("Advisor report obtained", slog.Int("hits", len(report.Hits)))
+	return report, nil
+}
@@ -0,0 +1,119 @@
+package advisor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Report is returned by GetReport.
+type Report struct {
+	// ETag identifies the version of the report.
+	ETag string
+	// NotModified is set when the report matches the ETag passed to GetReport.
+	// Hits are empty in such case.
+	NotModified bool
+	Hits        []Hit
+}
+
+// Hit object is returned by Advisor `/system/{id}/reports/` endpoint.
+type Hit struct {
+	Rule         Rule           `json:"rule"`
+	Details      map[string]any `json:"details"`
+	Resolution   Resolution     `json:"resolution"`
+	ImpactedDate time.Time      `json:"impacted_date"`
+}
+
+// KnowledgeBaseURL returns a link to the Knowledge base article of the rule.
+//
+// Empty string is returned if the rule has no article.
+func (h Hit) KnowledgeBaseURL() string {
+	if h.Rule.NodeID == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://access.redhat.com/node/%s", h.Rule.NodeID)
+}
+
+// Rule object is contained in Hit object.
+type Rule struct {
+	RuleID         string    `json:"rule_id"`
+	Description    string    `json:"description"`
+	Category       Category  `json:"category"`
+	Impact         Impact    `json:"impact"`
+	Likelihood     int       `json:"likelihood"`
+	TotalRisk      int       `json:"total_risk"`
+	RebootRequired bool      `json:"reboot_required"`
+	PublishDate    time.Time `json:"publish_date"`
+	Summary        string    `json:"summary"`
+	Generic        string    `json:"generic"`
+	Reason         string    `json:"reason"`
+	MoreInfo       string    `json:"more_info"`
+	NodeID         string    `json:"node_id"`
+	Tags           string    `json:"tags"`
+}
+
+// Category object is contained in Rule object.
+type Category struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+}
+
+// Impact object is contained in Rule object.
+type Impact struct {
+	Name   string `json:"name"`
+	Impact int    `json:"impact"`
+}
+
+// Resolution object is contained in Hit object.
+type Resolution struct {
+	SystemType  int    `json:"system_type"`
+	Resolution  string `json:"resolution"`
+	Risk        Risk   `json:"resolution_risk"`
+	HasPlaybook bool   `json:"has_playbook"`
+}
+
+// Risk object is contained in Resolution object.
+type Risk struct {
+	Name string `json:"name"`
+	Risk int    `json:"risk"`
+}
+
+// Severity is a human-readable representation of the rule's total risk.
+type Severity int
+
+const (
+	SeverityLow Severity = iota + 1
+	SeverityModerate
+	SeverityImportant
+	SeverityCritical
+)
+
+var severityNames = map[Severity]string{
+	SeverityLow:       "Low",
+	SeverityModerate:  "Moderate",
+	SeverityImportant: "Important",
+	SeverityCritical:  "Critical",
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// ParseSeverity converts the severity name into a Severity.
+func ParseSeverity(value string) (Severity, error) {
+	for severity, name := range severityNames {
+		if strings.EqualFold(name, value) {
+			return severity, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity '%s'", value)
+}
+
+// Severity returns the severity of the rule.
+func (r Rule) Severity() Severity {
+	return Severity(r.TotalRisk)
+}
@@ -0,0 +1,18 @@
+package advisor
+
+import (
+	"fmt"
+)
+
+func getHumanErrorOnNon200(value int) string {
+	switch value {
+	case 401:
+		return fmt.Sprintf("Advisor rejected unauthorized request (status code %d).", value)
+	case 403:
+		return fmt.Sprintf("Advisor rejected forbidden request (status code %d).", value)
+	case 404:
+		return fmt.Sprintf("Advisor does not know this host (status code %d).", value)
+	default:
+		return fmt.Sprintf("Advisor rejected the request (status code %d).", value)
+	}
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authenticator adds credentials of the host to API requests.
+type Authenticator interface {
+	// Configure prepares the TLS configuration of the client, e.g. adds client certificates.
+	Configure(config *tls.Config) IError
+	// Authenticate adds credentials to the request.
+	// The client may be used to obtain the credentials.
+	Authenticate(request *http.Request, client *http.Client) IError
+}
+
+// CertificateAuthenticator authenticates with mTLS, using the identity certificate of the host.
+type CertificateAuthenticator struct {
+	Certificate string
+	Key         string
+}
+
+// Configure refuses certificates that are expired or do not match their key.
+func (a *CertificateAuthenticator) Configure(config *tls.Config) IError {
+	info, inspectErr := InspectCertificate(a.Certificate, a.Key)
+	if inspectErr != nil {
+		return inspectErr
+	}
+	if inspectErr = info.Validate(time.Now()); inspectErr != nil {
+		slog.Error("identity certificate cannot be used", slog.String("error", inspectErr.Error()))
+		return inspectErr
+	}
+
+	cert, err := tls.LoadX509KeyPair(a.Certificate, a.Key)
+	if err != nil {
+		slog.Error("could not load identity certificate", slog.String("error", err.Error()))
+		return NewError(ErrNoCertificate, err, nil, "Could not load identity certificate.")
+	}
+	config.Certificates = append(config.Certificates, cert)
+	return nil
+}
+
+func (a *CertificateAuthenticator) Authenticate(*http.Request, *http.Client) IError {
+	return nil
+}
+
+// BasicAuthenticator authenticates with a username and a password.
+type BasicAuthenticator struct {
+	Username string
+	Password string
+}
+
+func (a *BasicAuthenticator) Configure(*tls.Config) IError {
+	return nil
+}
+
+func (a *BasicAuthenticator) Authenticate(request *http.Request, _ *http.Client) IError {
+	request.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// tokenExpiryMargin is subtracted from the token lifetime, so it does not expire during the request.
+const tokenExpiryMargin = 30 * time.Second
+
+// defaultTokenLifetime is used when the token endpoint does not report the lifetime of the token.
+const defaultTokenLifetime = 5 * time.Minute
+
+// TokenAuthenticator authenticates with a bearer token of a service account.
+//
+// The token is obtained from TokenURL with the OAuth2 client credentials flow,
+// and it is requested again once it expires. It is safe for concurrent use.
+type TokenAuthenticator struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	// Scopes are requested for the token. If empty, the default scopes of the account are granted.
+	Scopes []string
+
+	lock   sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// tokenResponse is a successful response of the token endpoint.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func (a *TokenAuthenticator) Configure(*tls.Config) IError {
+	return nil
+}
+
+func (a *TokenAuthenticator) Authenticate(request *http.Request, client *http.Client) IError {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.token == "" || time.Now().After(a.expiry) {
+		if err := a.refresh(client); err != nil {
+			return err
+		}
+	}
+	request.Header.Set("Authorization", "Bearer "+a.token)
+	return nil
+}
+
+// refresh requests a new token from the token endpoint.
+func (a *TokenAuthenticator) refresh(client *http.Client) IError {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", a.ClientID)
+	form.Set("client_secret", a.ClientSecret)
+	if len(a.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.Scopes, " "))
+	}
+
+	slog.Debug("requesting token", slog.String("URL", a.TokenURL), slog.String("client_id", a.ClientID))
+	resp, err := client.PostForm(a.TokenURL, form)
+	if err != nil {
+		slog.Error("could not request token", slog.String("error", err.Error()))
+		return NewError(ErrRequest, err, nil, "Could not request authentication token.")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return NewError(ErrBadResponse, fmt.Errorf("token endpoint returned %d", resp.StatusCode), nil, "Authentication token was not granted.")
+	}
+
+	var token tokenResponse
+	if err = json.NewDecoder(resp.Body).Decode(&token); err != nil || token.AccessToken == "" {
+		return NewError(ErrUnparseable, err, nil, "Could not parse authentication token.")
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return NewError(ErrBadResponse, fmt.Errorf("token type is %s", token.TokenType), nil, "Authentication token is not supported.")
+	}
+
+	a.token = token.AccessToken
+	lifetime := time.Duration(token.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = defaultTokenLifetime
+	}
+	a.expiry = time.Now().Add(lifetime - tokenExpiryMargin)
+	slog.Debug("token granted", slog.Time("expiry", a.expiry))
+	return nil
+}
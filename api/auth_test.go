@@ -0,0 +1,50 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTokenAuthenticator(t *testing.T) {
+	tests := []struct {
+		ExpiresIn int
+		Requests  int
+	}{
+		{3600, 1},
+		// tokens shorter than the expiry margin are requested for every request
+		{10, 2},
+		// missing lifetime uses the default one
+		{0, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("expires_in=%d", test.ExpiresIn), func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if r.PostFormValue("grant_type") != "client_credentials" || r.PostFormValue("client_secret") != "secret" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "Bearer", "expires_in": %d}`, requests, test.ExpiresIn)
+			}))
+			defer server.Close()
+
+			authenticator := &TokenAuthenticator{TokenURL: server.URL, ClientID: "id", ClientSecret: "secret"}
+			for i := 0; i < 2; i++ {
+				request, _ := http.NewRequest("GET", "https://example.com", nil)
+				if err := authenticator.Authenticate(request, server.Client()); err != nil {
+					t.Fatalf("expected 'nil', got '%v'", err)
+				}
+				if header := request.Header.Get("Authorization"); header == "" {
+					t.Errorf("expected bearer token, got '%s'", header)
+				}
+			}
+			if requests != test.Requests {
+				t.Errorf("expected %d token requests, got %d", test.Requests, requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// CertificateInfo describes an identity certificate and its private key.
+type CertificateInfo struct {
+	Path      string    `json:"path"`
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	Serial    string    `json:"serial"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	KeyType   string    `json:"key_type"`
+	// KeyMatches is set when the private key belongs to the certificate.
+	KeyMatches bool `json:"key_matches"`
+}
+
+// InspectCertificate reads the certificate and checks it against its private key.
+func InspectCertificate(certPath, keyPath string) (*CertificateInfo, IError) {
+	certData, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, NewError(ErrNoCertificate, err, nil, fmt.Sprintf("Could not read identity certificate '%s'.", certPath))
+	}
+	block, _ := pem.Decode(certData)
+	if block == nil {
+		return nil, NewError(ErrNoCertificate, errors.New("no PEM data found"), nil, fmt.Sprintf("Identity certificate '%s' is not valid.", certPath))
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, NewError(ErrNoCertificate, err, nil, fmt.Sprintf("Identity certificate '%s' is not valid.", certPath))
+	}
+	keyData, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, NewError(ErrNoCertificate, err, nil, fmt.Sprintf("Could not read identity key '%s'.", keyPath))
+	}
+	_, err = tls.X509KeyPair(certData, keyData)
+
+	return &CertificateInfo{
+		Path:       certPath,
+		Subject:    cert.Subject.String(),
+		Issuer:     cert.Issuer.String(),
+		Serial:     cert.SerialNumber.String(),
+		NotBefore:  cert.NotBefore,
+		NotAfter:   cert.NotAfter,
+		KeyType:    describePublicKey(cert.PublicKey),
+		KeyMatches: err == nil,
+	}, nil
+}
+
+// describePublicKey returns the algorithm and the size of the key, e.g. `RSA 2048`.
+func describePublicKey(key any) string {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ECDSA %s", key.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return "unknown"
+	}
+}
+
+// Validate reports why the certificate cannot be used at the time.
+func (c *CertificateInfo) Validate(now time.Time) IError {
+	if !c.KeyMatches {
+		return NewError(
+			ErrNoCertificate, errors.New("private key does not match the certificate"), nil,
+			fmt.Sprintf("Identity certificate '%s' does not match its private key.", c.Path),
+		)
+	}
+	if now.Before(c.NotBefore) {
+		return NewError(
+			ErrNoCertificate, fmt.Errorf("certificate is valid from %s", c.NotBefore), nil,
+			fmt.Sprintf("Identity certificate '%s' is not valid until %s. Check the system clock.", c.Path, c.NotBefore.Format(time.DateOnly)),
+		)
+	}
+	if now.After(c.NotAfter) {
+		return NewError(
+			ErrNoCertificate, fmt.Errorf("certificate expired at %s", c.NotAfter), nil,
+			fmt.Sprintf("Identity certificate '%s' expired on %s. Register the host with subscription-manager again.", c.Path, c.NotAfter.Format(time.DateOnly)),
+		)
+	}
+	return nil
+}
+
+// ExpiresWithin reports whether the certificate expires before the duration passes.
+func (c *CertificateInfo) ExpiresWithin(now time.Time, duration time.Duration) bool {
+	return now.Add(duration).After(c.NotAfter)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate creates a self-signed certificate valid between the times.
+//
+// When mismatch is set, the key written next to it does not belong to the certificate.
+func writeCertificate(t *testing.T, notBefore, notAfter time.Time, mismatch bool) (string, string) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "00000000-0000-0000-0000-000000000000"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mismatch {
+		key, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	directory := t.TempDir()
+	certPath := filepath.Join(directory, "cert.pem")
+	keyPath := filepath.Join(directory, "key.pem")
+	_ = os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	_ = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certPath, keyPath
+}
+
+func TestInspectCertificate(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+	tests := []struct {
+		Name      string
+		NotBefore time.Time
+		NotAfter  time.Time
+		Mismatch  bool
+		Valid     bool
+		Expiring  bool
+	}{
+		{"valid", now.Add(-day), now.Add(365 * day), false, true, false},
+		{"expiring", now.Add(-day), now.Add(7 * day), false, true, true},
+		{"expired", now.Add(-2 * day), now.Add(-day), false, false, true},
+		{"not yet valid", now.Add(day), now.Add(365 * day), false, false, false},
+		{"mismatch", now.Add(-day), now.Add(365 * day), true, false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			certPath, keyPath := writeCertificate(t, test.NotBefore, test.NotAfter, test.Mismatch)
+			info, err := InspectCertificate(certPath, keyPath)
+			if err != nil {
+				t.Fatalf("expected 'nil', got '%v'", err)
+			}
+			if info.Serial != "42" || info.KeyType != "ECDSA P-256" || info.KeyMatches == test.Mismatch {
+				t.Errorf("unexpected certificate info: %+v", info)
+			}
+
+			err = info.Validate(now)
+			if test.Valid && err != nil {
+				t.Errorf("expected 'nil', got '%v'", err)
+			}
+			if !test.Valid && (err == nil || !err.Is(ErrNoCertificate)) {
+				t.Errorf("expected '%v', got '%v'", ErrNoCertificate, err)
+			}
+			if expiring := info.ExpiresWithin(now, 30*day); expiring != test.Expiring {
+				t.Errorf("expected expiring '%t', got '%t'", test.Expiring, expiring)
+			}
+		})
+	}
+}
@@ -3,34 +3,47 @@ package api
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 )
 
 // NewAuthenticatedClient creates a client that uses mTLS authentication.
-func NewAuthenticatedClient(certPath, keyPath string, proxy *url.URL) (*http.Client, IError) {
-	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
-	if err != nil {
-		slog.Error("could not load identity certificate", slog.String("error", err.Error()))
-		return nil, NewError(ErrNoCertificate, err, nil, "Could not load identity certificate.")
-	}
-
-	caCert, err := os.ReadFile(certPath)
-	if err != nil {
-		slog.Error("could not load CA certificate", slog.String("error", err.Error()))
-		return nil, NewError(ErrNoCertificate, err, nil, "Could not load system certificates.")
-	}
+func NewAuthenticatedClient(certPath, keyPath, caPath string, proxy *url.URL) (*http.Client, IError) {
+	return NewClient(&CertificateAuthenticator{Certificate: certPath, Key: keyPath}, caPath, proxy)
+}
 
+// NewClient creates a client whose TLS configuration is prepared by the authenticator.
+//
+// Certificates found at caPath, a file or a directory of `*.pem` files, are trusted
+// in addition to the system ones. If caPath is empty or does not exist, only the system
+// certificates are trusted.
+func NewClient(authenticator Authenticator, caPath string, proxy *url.URL) (*http.Client, IError) {
 	pool, err := x509.SystemCertPool()
 	if err != nil {
 		slog.Error("could not load system certificate pool", slog.String("error", err.Error()))
 		return nil, NewError(ErrNoCertificate, err, nil, "Could not load system certificates.")
 	}
 
-	pool.AppendCertsFromPEM(caCert)
-	tlsConfig := &tls.Config{RootCAs: pool, Certificates: []tls.Certificate{cert}}
+	if caPath != "" {
+		if err := appendCertificates(pool, caPath); errors.Is(err, os.ErrNotExist) {
+			slog.Warn("CA certificate does not exist, only trusting system certificates", slog.String("path", caPath))
+		} else if err != nil {
+			slog.Error("could not load CA certificate", slog.String("path", caPath), slog.String("error", err.Error()))
+			return nil, NewError(ErrNoCertificate, err, nil, "Could not load CA certificate.")
+		}
+	}
+
+	tlsConfig := &tls.Config{RootCAs: pool}
+	if authenticator != nil {
+		if err := authenticator.Configure(tlsConfig); err != nil {
+			return nil, err
+		}
+	}
 
 	transport := &http.Transport{TLSClientConfig: tlsConfig}
 	if proxy != nil {
@@ -38,3 +51,39 @@ func NewAuthenticatedClient(certPath, keyPath string, proxy *url.URL) (*http.Cli
 	}
 	return &http.Client{Transport: transport}, nil
 }
+
+// appendCertificates adds certificates from the file, or from `*.pem` files in the directory, to the pool.
+//
+// Files of the directory that contain no certificate are skipped.
+func appendCertificates(pool *x509.CertPool, path string) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !stat.IsDir() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificate found in '%s'", path)
+		}
+		return nil
+	}
+
+	// Directories of certificate authorities may contain keys as well, e.g. `*-key.pem`.
+	paths, err := filepath.Glob(filepath.Join(path, "*.pem"))
+	if err != nil {
+		return err
+	}
+	for _, file := range paths {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			slog.Warn("skipping file without certificate", slog.String("path", file))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"crypto/x509"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendCertificates(t *testing.T) {
+	certPath, keyPath := writeCertificate(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), false)
+
+	// The key next to the certificate is skipped.
+	if err := appendCertificates(x509.NewCertPool(), filepath.Dir(certPath)); err != nil {
+		t.Errorf("expected 'nil', got '%v'", err)
+	}
+	if err := appendCertificates(x509.NewCertPool(), certPath); err != nil {
+		t.Errorf("expected 'nil', got '%v'", err)
+	}
+	if err := appendCertificates(x509.NewCertPool(), keyPath); err == nil {
+		t.Error("expected file without certificate to be refused")
+	}
+}
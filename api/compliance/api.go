@@ -0,0 +1,143 @@
+package compliance
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+
+	"github.com/m-horky/insights-client-next/api"
+)
+
+var service api.Service
+
+// Init has to be called to set up the API configuration for the service.
+func Init(s *api.Service) {
+	service = *s
+	service.Path = "api/compliance/v2"
+}
+
+// get sends a GET request and decodes the JSON response into `result`.
+func get(endpoint string, params url.Values, result any) api.IError {
+	response, err := service.MakeRequest("GET", endpoint, params, map[string][]string{}, nil)
+	if err != nil {
+		slog.Error("could not contact Compliance", slog.String("error", err.Error()))
+		return api.NewError(
+			api.ErrServiceUnreachable,
+			err,
+			nil,
+			"Compliance could not be contacted.",
+		)
+	}
+
+	if response.Code != 200 {
+		slog.Error("Compliance request failed", slog.String("raw response", string(response.Data)))
+		return api.NewError(
+			api.ErrBadResponse,
+			nil,
+			response,
+			getHumanErrorOnNon200(response.Code),
+		)
+	}
+
+	if err := json.Unmarshal(response.Data, result); err != nil {
+		slog.Error("could not unmarshal response", slog.String("error", err.Error()))
+		return api.NewError(
+			api.ErrUnparseable,
+			err,
+			response,
+			"Compliance response is malformed.",
+		)
+	}
+	return nil
+}
+
+// GetPolicies returns SCAP policies the host is assigned to.
+func GetPolicies(insightsInventoryID string) ([]Policy, api.IError) {
+	slog.Debug("querying Compliance for host policies")
+
+	var policies Policies
+	if err := get(fmt.Sprintf("systems/%s/policies", insightsInventoryID), url.Values{}, &policies); err != nil {
+		return nil, err
+	}
+	slog.Debug("Compliance policies obtained", slog.Int("count", len(policies.Data)))
+	return policies.Data, nil
+}
+
+// GetTailorings returns tailorings of a policy, one for each supported OS minor version.
+func GetTailorings(policyID string) ([]Tailoring, api.IError) {
+	slog.Debug("querying Compliance for policy tailorings", slog.String("policy", policyID))
+
+	var tailorings Tailorings
+	if err := get(fmt.Sprintf("policies/%s/tailorings", url.PathEscape(policyID)), url.Values{}, &tailorings); err != nil {
+		return nil, err
+	}
+	return tailorings.Data, nil
+}
+
+// DownloadTailoringFile saves the tailoring file to a path.
+func DownloadTailoringFile(policyID, tailoringID, path string) api.IError {
+	slog.Debug(
+		"downloading tailoring file",
+		slog.String("policy", policyID),
+		slog.String("tailoring", tailoringID),
+	)
+
+	endpoint := fmt.Sprintf("policies/%s/tailorings/%s/tailoring_file", url.PathEscape(policyID), url.PathEscape(tailoringID))
+	response, err := service.MakeRequest("GET", endpoint, url.Values{}, map[string][]string{"Accept": {"application/xml"}}, nil)
+	if err != nil {
+		slog.Error("could not contact Compliance", slog.String("error", err.Error()))
+		return api.NewError(
+			api.ErrServiceUnreachable,
+			err,
+			nil,
+			"Compliance could not be contacted.",
+		)
+	}
+
+	if response.Code != 200 {
+		slog.Error("Compliance request failed", slog.String("raw response", string(response.Data)))
+		return api.NewError(
+			api.ErrBadResponse,
+			nil,
+			response,
+			getHumanErrorOnNon200(response.Code),
+		)
+	}
+
+	if err := os.WriteFile(path, response.Data, 0o600); err != nil {
+		slog.Error("could not write tailoring file", slog.String("error", err.Error()))
+		return api.NewError(
+			ErrTailoring,
+			err,
+			response,
+			"Could not save the tailoring file.",
+		)
+	}
+	return nil
+}
+
+// GetLatestTestResult returns the most recent result of the host for a policy.
+func GetLatestTestResult(insightsInventoryID, policyID string) (*TestResult, api.IError) {
+	slog.Debug("querying Compliance for latest test result", slog.String("policy", policyID))
+
+	params := url.Values{}
+	params.Set("filter", fmt.Sprintf("system_id=%s", insightsInventoryID))
+	params.Set("sort_by", "end_time:desc")
+	params.Set("limit", "1")
+
+	var results TestResults
+	if err := get(fmt.Sprintf("reports/%s/test_results", url.PathEscape(policyID)), params, &results); err != nil {
+		return nil, err
+	}
+	if len(results.Data) == 0 {
+		return nil, api.NewError(
+			ErrNoResult,
+			nil,
+			nil,
+			"Compliance has no results for this host.",
+		)
+	}
+	return &results.Data[0], nil
+}
@@ -0,0 +1,53 @@
+package compliance
+
+import (
+	"time"
+)
+
+// Policies object is returned by Compliance `/systems/{id}/policies` endpoint.
+type Policies struct {
+	Data []Policy `json:"data"`
+}
+
+// Policy object is contained in Policies object.
+type Policy struct {
+	ID                  string  `json:"id"`
+	Title               string  `json:"title"`
+	Description         string  `json:"description"`
+	BusinessObjective   string  `json:"business_objective"`
+	ComplianceThreshold float64 `json:"compliance_threshold"`
+	OSMajorVersion      int     `json:"os_major_version"`
+	ProfileTitle        string  `json:"profile_title"`
+	// RefID is the ID of the SCAP profile the policy is based on.
+	RefID string `json:"ref_id"`
+}
+
+// Tailorings object is returned by Compliance `/policies/{id}/tailorings` endpoint.
+type Tailorings struct {
+	Data []Tailoring `json:"data"`
+}
+
+// Tailoring object is contained in Tailorings object.
+type Tailoring struct {
+	ID             string `json:"id"`
+	ProfileID      string `json:"profile_id"`
+	SecurityGuide  string `json:"security_guide_id"`
+	OSMajorVersion int    `json:"os_major_version"`
+	OSMinorVersion int    `json:"os_minor_version"`
+	RefID          string `json:"ref_id"`
+}
+
+// TestResults object is returned by Compliance `/reports/{id}/test_results` endpoint.
+type TestResults struct {
+	Data []TestResult `json:"data"`
+}
+
+// TestResult object is contained in TestResults object.
+type TestResult struct {
+	ID        string    `json:"id"`
+	SystemID  string    `json:"system_id"`
+	Score     float64   `json:"score"`
+	Compliant bool      `json:"compliant"`
+	Supported bool      `json:"supported"`
+	EndTime   time.Time `json:"end_time"`
+}
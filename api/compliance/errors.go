@@ -0,0 +1,24 @@
+package compliance
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNoResult  = errors.New("no test result exists")
+	ErrTailoring = errors.New("tailoring file could not be saved")
+)
+
+func getHumanErrorOnNon200(value int) string {
+	switch value {
+	case 401:
+		return fmt.Sprintf("Compliance rejected unauthorized request (status code %d).", value)
+	case 403:
+		return fmt.Sprintf("Compliance rejected forbidden request (status code %d).", value)
+	case 404:
+		return fmt.Sprintf("Compliance could not find the requested object (status code %d).", value)
+	default:
+		return fmt.Sprintf("Compliance rejected the request (status code %d).", value)
+	}
+}
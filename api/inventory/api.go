@@ -99,13 +99,15 @@ func DeleteHost(insightsInventoryID string) api.IError {
 	return nil
 }
 
-// UpdateDisplayName changes the name of the host displayed in Inventory.
-func UpdateDisplayName(insightsInventoryID, displayName string) api.IError {
-	slog.Debug("updating HBI host's display name", slog.String("name", displayName))
+// UpdateHost changes the mutable fields of the host record in Inventory.
+//
+// The host record is re-read after the update to ensure the changes were applied.
+func UpdateHost(insightsInventoryID string, patch HostPatch) api.IError {
+	slog.Debug("updating HBI host", slog.Any("patch", patch))
 
 	endpoint := fmt.Sprintf("hosts/%s", insightsInventoryID)
 
-	body, err := json.Marshal(map[string]string{"display_name": displayName})
+	body, err := json.Marshal(patch)
 	if err != nil {
 		slog.Error("could not encode payload", slog.String("error", err.Error()))
 		return api.NewError(
@@ -134,7 +136,7 @@ func UpdateDisplayName(insightsInventoryID, displayName string) api.IError {
 	}
 
 	if response.Code != 200 {
-		slog.Error("could not update host's display name", slog.Any("raw response", string(response.Data)))
+		slog.Error("could not update host", slog.Any("raw response", string(response.Data)))
 		return api.NewError(
 			api.ErrBadResponse,
 			nil,
@@ -142,16 +144,118 @@ func UpdateDisplayName(insightsInventoryID, displayName string) api.IError {
 			"Host inventory returned bad response.",
 		)
 	}
+
+	host, apiErr := getHostByID(insightsInventoryID)
+	if apiErr != nil {
+		return apiErr
+	}
+	if !patch.IsAppliedTo(host) {
+		slog.Error("HBI host was not updated", slog.Any("patch", patch), slog.Any("host", host))
+		return api.NewError(
+			ErrNotUpdated,
+			nil,
+			response,
+			"Host inventory did not apply the changes.",
+		)
+	}
 	return nil
 }
 
-// UpdateAnsibleHostname changes the name of the host displayed in Inventory.
-func UpdateAnsibleHostname(insightsInventoryID, ansibleHostname string) api.IError {
-	slog.Debug("updating HBI host's display name", slog.String("name", ansibleHostname))
+// GetSystemProfile returns the system profile of a host.
+func GetSystemProfile(insightsInventoryID string) (*SystemProfile, api.IError) {
+	slog.Debug("querying HBI for a system profile")
 
-	endpoint := fmt.Sprintf("hosts/%s", insightsInventoryID)
+	endpoint := fmt.Sprintf("hosts/%s/system_profile", insightsInventoryID)
+	response, err := service.MakeRequest("GET", endpoint, url.Values{}, map[string][]string{}, nil)
+	if err != nil {
+		slog.Error("could not contact HBI", slog.String("error", err.Error()))
+		return nil, err
+	}
 
-	body, err := json.Marshal(map[string]string{"ansible_host": ansibleHostname})
+	if response.Code != 200 {
+		slog.Error("HBI request failed", slog.String("raw response", string(response.Data)))
+		return nil, api.NewError(
+			api.ErrBadResponse,
+			nil,
+			response,
+			getHumanErrorOnNon200(response.Code),
+		)
+	}
+
+	var profiles SystemProfiles
+	if err := json.Unmarshal(response.Data, &profiles); err != nil {
+		slog.Error("could not unmarshal response", slog.String("error", err.Error()))
+		return nil, api.NewError(
+			api.ErrUnparseable,
+			err,
+			response,
+			"Host inventory response is malformed.",
+		)
+	}
+	if len(profiles.Results) == 0 {
+		slog.Debug("HBI returned no system profiles")
+		return nil, api.NewError(
+			ErrNoHost,
+			nil,
+			response,
+			"Host inventory returned no records.",
+		)
+	}
+
+	return &profiles.Results[0].SystemProfile, nil
+}
+
+// GetFacts returns custom facts of a host stored in a namespace.
+//
+// Empty map is returned if the namespace does not exist.
+func GetFacts(insightsInventoryID, namespace string) (map[string]any, api.IError) {
+	slog.Debug("querying HBI for host facts", slog.String("namespace", namespace))
+
+	endpoint := fmt.Sprintf("hosts/%s/facts/%s", insightsInventoryID, url.PathEscape(namespace))
+	response, err := service.MakeRequest("GET", endpoint, url.Values{}, map[string][]string{}, nil)
+	if err != nil {
+		slog.Error("could not contact HBI", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	if response.Code == 404 {
+		slog.Debug("HBI host has no such facts namespace", slog.String("namespace", namespace))
+		return map[string]any{}, nil
+	}
+	if response.Code != 200 {
+		slog.Error("HBI request failed", slog.String("raw response", string(response.Data)))
+		return nil, api.NewError(
+			api.ErrBadResponse,
+			nil,
+			response,
+			getHumanErrorOnNon200(response.Code),
+		)
+	}
+
+	var facts HostsFacts
+	if err := json.Unmarshal(response.Data, &facts); err != nil {
+		slog.Error("could not unmarshal response", slog.String("error", err.Error()))
+		return nil, api.NewError(
+			api.ErrUnparseable,
+			err,
+			response,
+			"Host inventory response is malformed.",
+		)
+	}
+	if len(facts.Results) == 0 || facts.Results[0].Facts == nil {
+		slog.Debug("HBI host has no such facts namespace", slog.String("namespace", namespace))
+		return map[string]any{}, nil
+	}
+	return facts.Results[0].Facts, nil
+}
+
+// UpdateFacts merges custom facts into a namespace of a host.
+func UpdateFacts(insightsInventoryID, namespace string, facts map[string]any) api.IError {
+	slog.Debug("updating HBI host facts", slog.String("namespace", namespace), slog.Any("facts", facts))
+
+	endpoint := fmt.Sprintf("hosts/%s/facts/%s", insightsInventoryID, url.PathEscape(namespace))
+
+	body, err := json.Marshal(facts)
 	if err != nil {
 		slog.Error("could not encode payload", slog.String("error", err.Error()))
 		return api.NewError(
@@ -180,17 +284,58 @@ func UpdateAnsibleHostname(insightsInventoryID, ansibleHostname string) api.IErr
 	}
 
 	if response.Code != 200 {
-		slog.Error("could not update host's display name", slog.Any("raw response", string(response.Data)))
+		slog.Error("could not update host's facts", slog.Any("raw response", string(response.Data)))
 		return api.NewError(
 			api.ErrBadResponse,
 			nil,
 			response,
-			"Host inventory returned bad response.",
+			getHumanErrorOnNon200(response.Code),
 		)
 	}
 	return nil
 }
 
+// getHostByID returns full host record from Inventory.
+func getHostByID(insightsInventoryID string) (*Host, api.IError) {
+	response, err := service.MakeRequest("GET", fmt.Sprintf("hosts/%s", insightsInventoryID), url.Values{}, map[string][]string{}, nil)
+	if err != nil {
+		slog.Error("could not contact HBI", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	if response.Code != 200 {
+		slog.Error("HBI request failed", slog.String("raw response", string(response.Data)))
+		return nil, api.NewError(
+			api.ErrBadResponse,
+			nil,
+			response,
+			getHumanErrorOnNon200(response.Code),
+		)
+	}
+
+	var hosts Hosts
+	if err := json.Unmarshal(response.Data, &hosts); err != nil {
+		slog.Error("could not unmarshal response", slog.String("error", err.Error()))
+		return nil, api.NewError(
+			api.ErrUnparseable,
+			err,
+			response,
+			"Host inventory response is malformed.",
+		)
+	}
+	if len(hosts.Results) == 0 {
+		slog.Debug("HBI returned no hosts")
+		return nil, api.NewError(
+			ErrNoHost,
+			nil,
+			response,
+			"Host inventory returned no records.",
+		)
+	}
+
+	return &hosts.Results[0], nil
+}
+
 // CheckIn sends in a minimal set of host information.
 func CheckIn() api.IError {
 	slog.Debug("collecting canonical facts")
@@ -0,0 +1,61 @@
+package inventory
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/m-horky/insights-client-next/api"
+)
+
+func TestGetFacts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/inventory/v1/hosts/host-id/facts/custom":
+			_, _ = w.Write([]byte(`{"total": 1, "count": 1, "page": 1, "per_page": 50, "results": [{"id": "host-id", "facts": {"rack": "A1"}}]}`))
+		case "/api/inventory/v1/hosts/host-id/facts/empty":
+			_, _ = w.Write([]byte(`{"total": 0, "count": 0, "page": 1, "per_page": 50, "results": []}`))
+		case "/api/inventory/v1/hosts/host-id/facts/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	address, _ := url.Parse(server.URL)
+	Init(api.NewService(address))
+
+	tests := []struct {
+		Namespace string
+		Expected  map[string]any
+		Error     error
+	}{
+		{"custom", map[string]any{"rack": "A1"}, nil},
+		{"empty", map[string]any{}, nil},
+		{"missing", map[string]any{}, nil},
+		{"broken", nil, api.ErrBadResponse},
+	}
+	for _, test := range tests {
+		t.Run(test.Namespace, func(t *testing.T) {
+			facts, err := GetFacts("host-id", test.Namespace)
+			if test.Error != nil {
+				if err == nil || !err.Is(test.Error) {
+					t.Fatalf("expected '%v', got '%v'", test.Error, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected 'nil', got '%v'", err)
+			}
+			if !reflect.DeepEqual(facts, test.Expected) {
+				t.Errorf("expected '%v', got '%v'", test.Expected, facts)
+			}
+		})
+	}
+}
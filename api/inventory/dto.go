@@ -1,6 +1,7 @@
 package inventory
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -31,7 +32,7 @@ type Host struct {
 	AnsibleHost           string                       `json:"ansible_host"`
 	Groups                []map[string]string          `json:"groups"`
 	Tags                  []any                        `json:"tags"`
-	Facts                 []any                        `json:"facts"`
+	Facts                 []Facts                      `json:"facts"`
 	Reporter              string                       `json:"reporter"`
 	PerReporterStaleness  map[string]ReporterStaleness `json:"per_reporter_staleness"`
 	StaleTimestamp        time.Time                    `json:"stale_timestamp"`
@@ -49,7 +50,118 @@ type ReporterStaleness struct {
 	CulledTimestamp       time.Time `json:"culled_timestamp"`
 }
 
+// HostPatch is an argument for UpdateHost.
+//
+// Only fields that are not nil are sent to Inventory.
+type HostPatch struct {
+	DisplayName *string `json:"display_name,omitempty"`
+	AnsibleHost *string `json:"ansible_host,omitempty"`
+}
+
+// HostPatchFields lists names of Host fields that can be updated.
+var HostPatchFields = []string{"display_name", "ansible_host"}
+
+// Set updates the patch field by its Inventory name.
+func (p *HostPatch) Set(field, value string) error {
+	switch field {
+	case "display_name":
+		p.DisplayName = &value
+	case "ansible_host":
+		p.AnsibleHost = &value
+	default:
+		return fmt.Errorf("field '%s' cannot be updated", field)
+	}
+	return nil
+}
+
+// IsEmpty reports whether the patch contains no changes.
+func (p *HostPatch) IsEmpty() bool {
+	return p.DisplayName == nil && p.AnsibleHost == nil
+}
+
+// IsAppliedTo reports whether the host record contains all changes of the patch.
+func (p *HostPatch) IsAppliedTo(host *Host) bool {
+	if p.DisplayName != nil && *p.DisplayName != host.DisplayName {
+		return false
+	}
+	if p.AnsibleHost != nil && *p.AnsibleHost != host.AnsibleHost {
+		return false
+	}
+	return true
+}
+
 // HostID object is returned by Inventory `/host_exists` endpoint.
 type HostID struct {
 	InsightsInventoryID string `json:"id"`
 }
+
+// Facts object is contained in Host object.
+//
+// Each namespace holds a flat set of custom facts.
+type Facts struct {
+	Namespace string         `json:"namespace"`
+	Facts     map[string]any `json:"facts"`
+}
+
+// HostsFacts object is returned by Inventory `/hosts/{id}/facts/{namespace}` endpoint.
+type HostsFacts struct {
+	Total   uint64      `json:"total"`
+	Count   uint64      `json:"count"`
+	Page    uint64      `json:"page"`
+	PerPage uint64      `json:"per_page"`
+	Results []HostFacts `json:"results"`
+}
+
+// HostFacts object is contained in HostsFacts object.
+type HostFacts struct {
+	InsightsInventoryID string         `json:"id"`
+	Facts               map[string]any `json:"facts"`
+}
+
+// SystemProfiles object is returned by Inventory `/hosts/{id}/system_profile` endpoint.
+type SystemProfiles struct {
+	Total   uint64              `json:"total"`
+	Count   uint64              `json:"count"`
+	Page    uint64              `json:"page"`
+	PerPage uint64              `json:"per_page"`
+	Results []HostSystemProfile `json:"results"`
+}
+
+// HostSystemProfile object is contained in SystemProfiles object.
+type HostSystemProfile struct {
+	InsightsInventoryID string        `json:"id"`
+	SystemProfile       SystemProfile `json:"system_profile"`
+}
+
+// SystemProfile object is contained in HostSystemProfile object.
+//
+// Only a subset of the fields Inventory provides is included.
+type SystemProfile struct {
+	Arch                  string          `json:"arch,omitempty"`
+	OperatingSystem       OperatingSystem `json:"operating_system,omitempty"`
+	OSRelease             string          `json:"os_release,omitempty"`
+	OSKernelVersion       string          `json:"os_kernel_version,omitempty"`
+	InfrastructureType    string          `json:"infrastructure_type,omitempty"`
+	InfrastructureVendor  string          `json:"infrastructure_vendor,omitempty"`
+	BIOSVendor            string          `json:"bios_vendor,omitempty"`
+	BIOSVersion           string          `json:"bios_version,omitempty"`
+	NumberOfCPUs          uint64          `json:"number_of_cpus,omitempty"`
+	NumberOfSockets       uint64          `json:"number_of_sockets,omitempty"`
+	CoresPerSocket        uint64          `json:"cores_per_socket,omitempty"`
+	SystemMemoryBytes     uint64          `json:"system_memory_bytes,omitempty"`
+	CloudProvider         string          `json:"cloud_provider,omitempty"`
+	SELinuxCurrentMode    string          `json:"selinux_current_mode,omitempty"`
+	SystemUpdateMethod    string          `json:"system_update_method,omitempty"`
+	InsightsClientVersion string          `json:"insights_client_version,omitempty"`
+	InsightsEggVersion    string          `json:"insights_egg_version,omitempty"`
+	RHCClientID           string          `json:"rhc_client_id,omitempty"`
+	OwnerID               string          `json:"owner_id,omitempty"`
+	LastBootTime          *time.Time      `json:"last_boot_time,omitempty"`
+}
+
+// OperatingSystem object is contained in SystemProfile object.
+type OperatingSystem struct {
+	Name  string `json:"name"`
+	Major int    `json:"major"`
+	Minor int    `json:"minor"`
+}
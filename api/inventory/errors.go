@@ -6,8 +6,9 @@ import (
 )
 
 var (
-	ErrNoHost    = errors.New("host does not exist")
-	ErrManyHosts = errors.New("multiple hosts exist")
+	ErrNoHost     = errors.New("host does not exist")
+	ErrManyHosts  = errors.New("multiple hosts exist")
+	ErrNotUpdated = errors.New("host was not updated")
 )
 
 func getHumanErrorOnNon200(value int) string {
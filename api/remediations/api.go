@@ -0,0 +1,145 @@
+package remediations
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+
+	"github.com/m-horky/insights-client-next/api"
+)
+
+var service api.Service
+
+// Init has to be called to set up the API configuration for the service.
+func Init(s *api.Service) {
+	service = *s
+	service.Path = "api/remediations/v1"
+}
+
+// GetDiagnosis returns the remediation diagnosis for the host.
+func GetDiagnosis(insightsInventoryID string) (*Diagnosis, api.IError) {
+	slog.Debug("querying Remediations for a diagnosis")
+
+	endpoint := fmt.Sprintf("diagnosis/%s", insightsInventoryID)
+	response, err := service.MakeRequest("GET", endpoint, url.Values{}, map[string][]string{}, nil)
+	if err != nil {
+		slog.Error("could not contact Remediations", slog.String("error", err.Error()))
+		return nil, api.NewError(
+			api.ErrServiceUnreachable,
+			err,
+			nil,
+			"Remediations could not be contacted.",
+		)
+	}
+
+	if response.Code != 200 {
+		slog.Error("Remediations request failed", slog.String("raw response", string(response.Data)))
+		return nil, api.NewError(
+			api.ErrBadResponse,
+			nil,
+			response,
+			getHumanErrorOnNon200(response.Code),
+		)
+	}
+
+	var diagnosis Diagnosis
+	if err := json.Unmarshal(response.Data, &diagnosis); err != nil {
+		slog.Error("could not unmarshal response", slog.String("error", err.Error()))
+		return nil, api.NewError(
+			api.ErrUnparseable,
+			err,
+			response,
+			"Remediations response is malformed.",
+		)
+	}
+	return &diagnosis, nil
+}
+
+// GetRemediations returns remediation plans that include the host.
+func GetRemediations(insightsInventoryID string) ([]Remediation, api.IError) {
+	slog.Debug("querying Remediations for remediation plans")
+
+	params := url.Values{}
+	params.Set("system", insightsInventoryID)
+
+	response, err := service.MakeRequest("GET", "remediations", params, map[string][]string{}, nil)
+	if err != nil {
+		slog.Error("could not contact Remediations", slog.String("error", err.Error()))
+		return nil, api.NewError(
+			api.ErrServiceUnreachable,
+			err,
+			nil,
+			"Remediations could not be contacted.",
+		)
+	}
+
+	if response.Code != 200 {
+		slog.Error("Remediations request failed", slog.String("raw response", string(response.Data)))
+		return nil, api.NewError(
+			api.ErrBadResponse,
+			nil,
+			response,
+			getHumanErrorOnNon200(response.Code),
+		)
+	}
+
+	var remediations Remediations
+	if err := json.Unmarshal(response.Data, &remediations); err != nil {
+		slog.Error("could not unmarshal response", slog.String("error", err.Error()))
+		return nil, api.NewError(
+			api.ErrUnparseable,
+			err,
+			response,
+			"Remediations response is malformed.",
+		)
+	}
+	return remediations.Data, nil
+}
+
+// DownloadPlaybook saves the Ansible playbook of a remediation plan to a file.
+//
+// Only the host is included in the playbook.
+func DownloadPlaybook(remediationID, insightsInventoryID, path string) api.IError {
+	slog.Debug("downloading remediation playbook", slog.String("remediation", remediationID))
+
+	params := url.Values{}
+	params.Set("hosts", insightsInventoryID)
+
+	endpoint := fmt.Sprintf("remediations/%s/playbook", url.PathEscape(remediationID))
+	response, err := service.MakeRequest(
+		"GET", endpoint, params, map[string][]string{"Accept": {"text/vnd.yaml"}}, nil,
+	)
+	if err != nil {
+		slog.Error("could not contact Remediations", slog.String("error", err.Error()))
+		return api.NewError(
+			api.ErrServiceUnreachable,
+			err,
+			nil,
+			"Remediations could not be contacted.",
+		)
+	}
+
+	if response.Code != 200 {
+		slog.Error("Remediations request failed", slog.String("raw response", string(response.Data)))
+		return api.NewError(
+			api.ErrBadResponse,
+			nil,
+			response,
+			getHumanErrorOnNon200(response.Code),
+		)
+	}
+
+	if err := os.WriteFile(path, response.Data, 0o600); err != nil {
+		slog.Error("could not write playbook", slog.String("error", err.Error()))
+		return api.NewError(
+			ErrPlaybook,
+			err,
+			response,
+			"Could not save the playbook.",
+		)
+	}
+	slog.Debug("remediation playbook saved", slog.String("path", path))
+	return nil
+}
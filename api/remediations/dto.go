@@ -0,0 +1,38 @@
+package remediations
+
+import (
+	"time"
+)
+
+// Diagnosis object is returned by Remediations `/diagnosis/{id}` endpoint.
+type Diagnosis struct {
+	ID string `json:"id"`
+	// Insights maps Advisor rule IDs to details of the issue.
+	Insights map[string]map[string]any `json:"insights"`
+}
+
+// Remediations object is returned by Remediations `/remediations` endpoint.
+type Remediations struct {
+	Data []Remediation `json:"data"`
+	Meta Meta          `json:"meta"`
+}
+
+// Meta object is contained in Remediations object.
+type Meta struct {
+	Count int `json:"count"`
+	Total int `json:"total"`
+}
+
+// Remediation object is contained in Remediations object.
+type Remediation struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	NeedsReboot   bool      `json:"needs_reboot"`
+	AutoReboot    bool      `json:"auto_reboot"`
+	SystemCount   int       `json:"system_count"`
+	IssueCount    int       `json:"issue_count"`
+	ResolvedCount int       `json:"resolved_count"`
+	Archived      bool      `json:"archived"`
+	Created       time.Time `json:"created_at"`
+	Updated       time.Time `json:"updated_at"`
+}
@@ -0,0 +1,21 @@
+package remediations
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrPlaybook = errors.New("playbook could not be saved")
+
+func getHumanErrorOnNon200(value int) string {
+	switch value {
+	case 401:
+		return fmt.Sprintf("Remediations rejected unauthorized request (status code %d).", value)
+	case 403:
+		return fmt.Sprintf("Remediations rejected forbidden request (status code %d).", value)
+	case 404:
+		return fmt.Sprintf("Remediations could not find the requested object (status code %d).", value)
+	default:
+		return fmt.Sprintf("Remediations rejected the request (status code %d).", value)
+	}
+}
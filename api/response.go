@@ -2,11 +2,13 @@ package api
 
 import (
 	"fmt"
+	"net/http"
 )
 
 type Response struct {
-	Code int
-	Data []byte
+	Code   int
+	Header http.Header
+	Data   []byte
 }
 
 func (r Response) String() string {
@@ -0,0 +1,79 @@
+package rules
+
+import (
+	"log/slog"
+	"net/url"
+
+	"github.com/m-horky/insights-client-next/api"
+)
+
+var service api.Service
+
+// Init has to be called to set up the API configuration for the service.
+func Init(s *api.Service) {
+	service = *s
+	service.Path = "api/v1/static"
+}
+
+// RulesFile is the name of the collection rules document.
+const RulesFile = "uploader.v2.json"
+
+// GetRules downloads the collection rules and their signature.
+//
+// When the rules match `etag` or `lastModified`, only NotModified is set.
+func GetRules(etag, lastModified string) (*Rules, api.IError) {
+	slog.Debug("downloading collection rules", slog.String("etag", etag), slog.String("last modified", lastModified))
+
+	headers := map[string][]string{}
+	if etag != "" {
+		headers["If-None-Match"] = []string{etag}
+	}
+	if lastModified != "" {
+		headers["If-Modified-Since"] = []string{lastModified}
+	}
+	response, err := download(RulesFile, headers)
+	if err != nil {
+		return nil, err
+	}
+	if response.Code == 304 {
+		slog.Debug("collection rules have not changed")
+		return &Rules{ETag: etag, LastModified: lastModified, NotModified: true}, nil
+	}
+	rules := &Rules{
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+		Data:         response.Data,
+	}
+
+	response, err = download(RulesFile+".asc", map[string][]string{"Accept": {"text/plain"}})
+	if err != nil {
+		return nil, err
+	}
+	rules.Signature = response.Data
+	return rules, nil
+}
+
+// download fetches static content, accepting 200 and 304 responses.
+func download(endpoint string, headers map[string][]string) (*api.Response, api.IError) {
+	response, err := service.MakeRequest("GET", endpoint, url.Values{}, headers, nil)
+	if err != nil {
+		slog.Error("could not contact static content", slog.String("error", err.Error()))
+		return nil, api.NewError(
+			api.ErrServiceUnreachable,
+			err,
+			nil,
+			"Collection rules could not be downloaded.",
+		)
+	}
+
+	if response.Code != 200 && response.Code != 304 {
+		slog.Error("collection rules request failed", slog.Int("code", response.Code), slog.String("endpoint", endpoint))
+		return nil, api.NewError(
+			api.ErrBadResponse,
+			nil,
+			response,
+			getHumanErrorOnNon200(response.Code),
+		)
+	}
+	return response, nil
+}
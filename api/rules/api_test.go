@@ -0,0 +1,81 @@
+package rules
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/m-horky/insights-client-next/api"
+)
+
+func TestGetRules(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/static/uploader.v2.json":
+			if r.Header.Get("If-None-Match") == `"v2"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v2"`)
+			_, _ = w.Write([]byte(`{"version": "2"}`))
+		case "/api/v1/static/uploader.v2.json.asc":
+			_, _ = w.Write([]byte("signature"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	address, _ := url.Parse(server.URL)
+	Init(api.NewService(address))
+
+	tests := []struct {
+		ETag     string
+		Expected Rules
+	}{
+		{"", Rules{ETag: `"v2"`, Data: []byte(`{"version": "2"}`), Signature: []byte("signature")}},
+		{`"v1"`, Rules{ETag: `"v2"`, Data: []byte(`{"version": "2"}`), Signature: []byte("signature")}},
+		{`"v2"`, Rules{ETag: `"v2"`, NotModified: true}},
+	}
+
+	for _, test := range tests {
+		t.Run("etag="+test.ETag, func(t *testing.T) {
+			rules, err := GetRules(test.ETag, "")
+			if err != nil {
+				t.Fatalf("expected 'nil', got '%v'", err)
+			}
+			if rules.ETag != test.Expected.ETag || rules.NotModified != test.Expected.NotModified {
+				t.Errorf("expected '%+v', got '%+v'", test.Expected, *rules)
+			}
+			if string(rules.Data) != string(test.Expected.Data) || string(rules.Signature) != string(test.Expected.Signature) {
+				t.Errorf("expected '%s' signed by '%s', got '%s' signed by '%s'", test.Expected.Data, test.Expected.Signature, rules.Data, rules.Signature)
+			}
+		})
+	}
+}
+
+func TestGetRules_failure(t *testing.T) {
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	tests := []struct {
+		Name     string
+		URL      string
+		Expected error
+	}{
+		{"missing", missing.URL, api.ErrBadResponse},
+		{"unreachable", unreachable.URL, api.ErrServiceUnreachable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			address, _ := url.Parse(test.URL)
+			Init(api.NewService(address))
+			if _, err := GetRules("", ""); err == nil || !err.Is(test.Expected) {
+				t.Errorf("expected '%v', got '%v'", test.Expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package rules
+
+// Rules is returned by GetRules.
+type Rules struct {
+	// ETag and LastModified identify the version of the rules.
+	ETag         string
+	LastModified string
+	// NotModified is set when the rules match the validators passed to GetRules.
+	// Data and Signature are empty in such case.
+	NotModified bool
+	Data        []byte
+	// Signature is a detached ASCII-armored GPG signature of Data.
+	Signature []byte
+}
+
+// CollectionRules describe what the Core collects, parsed from Rules.Data.
+type CollectionRules struct {
+	Version  string        `json:"version"`
+	Commands []RuleCommand `json:"commands"`
+	Files    []RuleFile    `json:"files"`
+	Globs    []RuleGlob    `json:"globs"`
+}
+
+// RuleCommand is contained in CollectionRules.
+type RuleCommand struct {
+	Command      string   `json:"command"`
+	Pattern      []string `json:"pattern"`
+	SymbolicName string   `json:"symbolic_name"`
+}
+
+// RuleFile is contained in CollectionRules.
+type RuleFile struct {
+	File         string   `json:"file"`
+	Pattern      []string `json:"pattern"`
+	SymbolicName string   `json:"symbolic_name"`
+}
+
+// RuleGlob is contained in CollectionRules.
+type RuleGlob struct {
+	Glob         string   `json:"glob"`
+	Pattern      []string `json:"pattern"`
+	SymbolicName string   `json:"symbolic_name"`
+}
@@ -0,0 +1,18 @@
+package rules
+
+import (
+	"fmt"
+)
+
+func getHumanErrorOnNon200(value int) string {
+	switch value {
+	case 401:
+		return fmt.Sprintf("Collection rules could not be downloaded, the request is unauthorized (status code %d).", value)
+	case 403:
+		return fmt.Sprintf("Collection rules could not be downloaded, the request is forbidden (status code %d).", value)
+	case 404:
+		return fmt.Sprintf("Collection rules could not be found (status code %d).", value)
+	default:
+		return fmt.Sprintf("Collection rules could not be downloaded (status code %d).", value)
+	}
+}
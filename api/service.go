@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -15,13 +16,25 @@ import (
 //
 // To create an instance, use NewService.
 type Service struct {
-	URL               *url.URL
-	Path              string
-	ClientCertificate string
-	ClientKey         string
-	Proxy             *url.URL
+	URL  *url.URL
+	Path string
+	// Authenticator adds credentials to requests, see WithAuthenticator.
+	Authenticator Authenticator
+	// CACertificate is a file or a directory of certificates trusted in addition to the system ones.
+	CACertificate string
+	Proxy         *url.URL
+	// Timeout limits the time of each request. Zero means no limit.
+	Timeout time.Duration
+	// Satellite proxies the API, see WithSatellite.
+	Satellite bool
 }
 
+// Satellite (and Capsule) proxy the API under different paths.
+const (
+	satellitePlatformPath = "redhat_access/r/insights/platform/"
+	satelliteStaticPath   = "redhat_access/r/insights/v1/"
+)
+
 func NewService(address *url.URL) *Service {
 	return &Service{URL: address}
 }
@@ -30,7 +43,17 @@ func NewService(address *url.URL) *Service {
 func (s *Service) WithAuthentication(certificate, key string) *Service {
 	// TODO Should this make in-place change and only return an error instead?
 	// TODO How to handle non-existing certificate files?
-	return &Service{s.URL, s.Path, certificate, key, s.Proxy}
+	return s.WithAuthenticator(&CertificateAuthenticator{Certificate: certificate, Key: key})
+}
+
+// WithAuthenticator configures the service to authenticate with the authenticator.
+func (s *Service) WithAuthenticator(authenticator Authenticator) *Service {
+	return &Service{s.URL, s.Path, authenticator, s.CACertificate, s.Proxy, s.Timeout, s.Satellite}
+}
+
+// WithCA configures the service to trust additional certificate authorities.
+func (s *Service) WithCA(path string) *Service {
+	return &Service{s.URL, s.Path, s.Authenticator, path, s.Proxy, s.Timeout, s.Satellite}
 }
 
 // WithProxy configures the service to use a HTTP(S) proxy.
@@ -38,7 +61,7 @@ func (s *Service) WithProxy(address string) *Service {
 	// TODO Should this make in-place change and only return an error instead?
 	// TODO How to handle bad proxy input?
 
-	result := &Service{s.URL, s.Path, s.ClientCertificate, s.ClientKey, s.Proxy}
+	result := &Service{s.URL, s.Path, s.Authenticator, s.CACertificate, s.Proxy, s.Timeout, s.Satellite}
 	proxyURL, err := url.Parse(address)
 	if address == "" {
 		return result
@@ -51,14 +74,45 @@ func (s *Service) WithProxy(address string) *Service {
 	return result
 }
 
+// WithTimeout configures the service to give up on requests that take too long.
+func (s *Service) WithTimeout(timeout time.Duration) *Service {
+	return &Service{s.URL, s.Path, s.Authenticator, s.CACertificate, s.Proxy, timeout, s.Satellite}
+}
+
+// WithSatellite configures the service to talk to the API through Red Hat Satellite or Capsule.
+//
+// Satellite exposes the platform API (`api/inventory/v1`) under `redhat_access/r/insights/platform/`
+// and static files (`api/v1/static`) under `redhat_access/r/insights/v1/`.
+func (s *Service) WithSatellite(enabled bool) *Service {
+	return &Service{s.URL, s.Path, s.Authenticator, s.CACertificate, s.Proxy, s.Timeout, enabled}
+}
+
 // String formats the service into a URI.
 func (s *Service) String() string {
-	return fmt.Sprintf("%s://%s/%s", s.URL.Scheme, s.URL.Host, s.Path)
+	return fmt.Sprintf("%s://%s/%s", s.URL.Scheme, s.URL.Host, s.resolvePath(""))
+}
+
+// resolvePath joins the endpoint to the service path and maps it to the layout of the server.
+func (s *Service) resolvePath(endpoint string) string {
+	path := s.Path
+	if endpoint != "" {
+		path += "/" + endpoint
+	}
+	if !s.Satellite {
+		return path
+	}
+	if rest, found := strings.CutPrefix(path, "api/v1/"); found {
+		return satelliteStaticPath + rest
+	}
+	if rest, found := strings.CutPrefix(path, "api/"); found {
+		return satellitePlatformPath + rest
+	}
+	return path
 }
 
 // MakeRequest sends a request to a relevant service.
 //
-// This method uses RHSM certificates to authenticate to the server.
+// The request is authenticated by the Authenticator of the service.
 // Unless present, the `Accept` header is set to `application/json`.
 func (s *Service) MakeRequest(
 	method,
@@ -67,7 +121,7 @@ func (s *Service) MakeRequest(
 	headers map[string][]string,
 	body *bytes.Buffer,
 ) (*Response, IError) {
-	fullUrl := fmt.Sprintf("%s/%s?%s", s, endpoint, parameters.Encode())
+	fullUrl := fmt.Sprintf("%s://%s/%s?%s", s.URL.Scheme, s.URL.Host, s.resolvePath(endpoint), parameters.Encode())
 
 	if body == nil {
 		body = bytes.NewBuffer(nil)
@@ -86,16 +140,27 @@ func (s *Service) MakeRequest(
 		req.Header.Set("Accept", "application/json")
 	}
 
-	client, err := NewAuthenticatedClient(s.ClientCertificate, s.ClientKey, s.Proxy)
-	if err != nil {
-		slog.Error("could not create client", slog.String("error", err.Error()))
-		return nil, NewError(ErrRequest, err, nil, "Could not create API client.")
+	client, clientErr := NewClient(s.Authenticator, s.CACertificate, s.Proxy)
+	if clientErr != nil {
+		slog.Error("could not create client", slog.String("error", clientErr.Error()))
+		return nil, clientErr
+	}
+	client.Timeout = s.Timeout
+	if s.Authenticator != nil {
+		if err := s.Authenticator.Authenticate(req, client); err != nil {
+			return nil, err
+		}
 	}
 
 	{
-		attrs := []any{slog.String("method", method), slog.String("URL", fullUrl), slog.Any("headers", req.Header)}
+		// Credentials must not end up in logs.
+		loggedHeaders := req.Header.Clone()
+		if loggedHeaders.Get("Authorization") != "" {
+			loggedHeaders.Set("Authorization", "***")
+		}
+		attrs := []any{slog.String("method", method), slog.String("URL", fullUrl), slog.Any("headers", loggedHeaders)}
 		if s.Proxy != nil {
-			attrs = append(attrs, slog.String("proxy", s.Proxy.String()))
+			attrs = append(attrs, slog.String("proxy", s.Proxy.Redacted()))
 		}
 		slog.Debug("request sent", attrs...)
 	}
@@ -128,7 +193,7 @@ func (s *Service) MakeRequest(
 		slog.Debug("response data", slog.String("payload", stringifyData(response)))
 	}
 
-	return &Response{Code: resp.StatusCode, Data: response}, nil
+	return &Response{Code: resp.StatusCode, Header: resp.Header, Data: response}, nil
 }
 
 // stringifyData takes in a byte slice and converts it to string.
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestService_resolvePath(t *testing.T) {
+	tests := []struct {
+		Path      string
+		Endpoint  string
+		Satellite bool
+		Expected  string
+	}{
+		{"api/inventory/v1", "hosts", false, "api/inventory/v1/hosts"},
+		{"api/insights/v1", "system/x/reports/", false, "api/insights/v1/system/x/reports/"},
+		{"api/inventory/v1", "hosts", true, "redhat_access/r/insights/platform/inventory/v1/hosts"},
+		{"api/ingress/v1", "upload", true, "redhat_access/r/insights/platform/ingress/v1/upload"},
+		{"api/v1/static", "uploader.v2.json", true, "redhat_access/r/insights/v1/static/uploader.v2.json"},
+		{"api", "module-update-router/v1/channel", true, "redhat_access/r/insights/platform/module-update-router/v1/channel"},
+		{"api", "v1/static/release/insights-core.egg", true, "redhat_access/r/insights/v1/static/release/insights-core.egg"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Path+"/"+test.Endpoint, func(t *testing.T) {
+			service := NewService(&url.URL{Scheme: "https", Host: "satellite.example.com"}).WithSatellite(test.Satellite)
+			service.Path = test.Path
+			if path := service.resolvePath(test.Endpoint); path != test.Expected {
+				t.Errorf("expected '%s', got '%s'", test.Expected, path)
+			}
+		})
+	}
+}
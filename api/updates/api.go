@@ -0,0 +1,116 @@
+package updates
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/m-horky/insights-client-next/api"
+)
+
+var service api.Service
+
+// Init has to be called to set up the API configuration for the service.
+//
+// The service spans both the Module Update Router and the static content,
+// so its path only contains the common prefix.
+func Init(s *api.Service) {
+	service = *s
+	service.Path = "api"
+}
+
+// GetChannel returns the update channel of the module, e.g. `/release`.
+func GetChannel(module string) (string, api.IError) {
+	slog.Debug("querying Module Update Router for a channel", slog.String("module", module))
+
+	params := url.Values{}
+	params.Set("module", module)
+
+	response, err := service.MakeRequest("GET", "module-update-router/v1/channel", params, map[string][]string{}, nil)
+	if err != nil {
+		slog.Error("could not contact Module Update Router", slog.String("error", err.Error()))
+		return "", api.NewError(
+			api.ErrServiceUnreachable,
+			err,
+			nil,
+			"Update service could not be contacted.",
+		)
+	}
+
+	if response.Code != 200 {
+		slog.Error("Module Update Router request failed", slog.String("raw response", string(response.Data)))
+		return "", api.NewError(
+			api.ErrBadResponse,
+			nil,
+			response,
+			getHumanErrorOnNon200(response.Code),
+		)
+	}
+
+	var channel Channel
+	if err := json.Unmarshal(response.Data, &channel); err != nil {
+		slog.Error("could not unmarshal response", slog.String("error", err.Error()))
+		return "", api.NewError(
+			api.ErrUnparseable,
+			err,
+			response,
+			"Update service response is malformed.",
+		)
+	}
+	return channel.URL, nil
+}
+
+// GetEgg downloads the Core egg of the channel and its signature.
+//
+// When the egg matches `etag`, only NotModified is set.
+func GetEgg(channel, etag string) (*Egg, api.IError) {
+	slog.Debug("downloading Core egg", slog.String("channel", channel), slog.String("etag", etag))
+
+	endpoint := fmt.Sprintf("v1/static%s/insights-core.egg", channel)
+	headers := map[string][]string{"Accept": {"application/octet-stream"}}
+	if etag != "" {
+		headers["If-None-Match"] = []string{etag}
+	}
+	response, err := download(endpoint, headers)
+	if err != nil {
+		return nil, err
+	}
+	if response.Code == 304 {
+		slog.Debug("Core egg has not changed")
+		return &Egg{ETag: etag, NotModified: true}, nil
+	}
+	egg := &Egg{ETag: response.Header.Get("ETag"), Data: response.Data}
+
+	response, err = download(endpoint+".asc", map[string][]string{"Accept": {"text/plain"}})
+	if err != nil {
+		return nil, err
+	}
+	egg.Signature = response.Data
+	return egg, nil
+}
+
+// download fetches static content, accepting 200 and 304 responses.
+func download(endpoint string, headers map[string][]string) (*api.Response, api.IError) {
+	response, err := service.MakeRequest("GET", endpoint, url.Values{}, headers, nil)
+	if err != nil {
+		slog.Error("could not contact update service", slog.String("error", err.Error()))
+		return nil, api.NewError(
+			api.ErrServiceUnreachable,
+			err,
+			nil,
+			"Update service could not be contacted.",
+		)
+	}
+
+	if response.Code != 200 && response.Code != 304 {
+		slog.Error("update request failed", slog.Int("code", response.Code), slog.String("endpoint", endpoint))
+		return nil, api.NewError(
+			api.ErrBadResponse,
+			nil,
+			response,
+			getHumanErrorOnNon200(response.Code),
+		)
+	}
+	return response, nil
+}
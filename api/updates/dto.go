@@ -0,0 +1,19 @@
+package updates
+
+// Channel object is returned by Module Update Router `/channel` endpoint.
+type Channel struct {
+	// URL is a path of the channel under the static content, e.g. `/release`.
+	URL string `json:"url"`
+}
+
+// Egg is returned by GetEgg.
+type Egg struct {
+	// ETag identifies the version of the egg.
+	ETag string
+	// NotModified is set when the egg matches the ETag passed to GetEgg.
+	// Data and Signature are empty in such case.
+	NotModified bool
+	Data        []byte
+	// Signature is a detached ASCII-armored GPG signature of Data.
+	Signature []byte
+}
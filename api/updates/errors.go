@@ -0,0 +1,18 @@
+package updates
+
+import (
+	"fmt"
+)
+
+func getHumanErrorOnNon200(value int) string {
+	switch value {
+	case 401:
+		return fmt.Sprintf("Update service rejected unauthorized request (status code %d).", value)
+	case 403:
+		return fmt.Sprintf("Update service rejected forbidden request (status code %d).", value)
+	case 404:
+		return fmt.Sprintf("Update service could not find the requested object (status code %d).", value)
+	default:
+		return fmt.Sprintf("Update service rejected the request (status code %d).", value)
+	}
+}
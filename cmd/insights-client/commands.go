@@ -7,7 +7,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
-	"reflect"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -15,8 +15,13 @@ import (
 	"github.com/urfave/cli/v3"
 
 	"github.com/m-horky/insights-client-next/api"
+	"github.com/m-horky/insights-client-next/api/advisor"
+	"github.com/m-horky/insights-client-next/api/compliance"
 	"github.com/m-horky/insights-client-next/api/ingress"
 	"github.com/m-horky/insights-client-next/api/inventory"
+	"github.com/m-horky/insights-client-next/api/remediations"
+	"github.com/m-horky/insights-client-next/api/rules"
+	"github.com/m-horky/insights-client-next/api/updates"
 	"github.com/m-horky/insights-client-next/internal"
 	"github.com/m-horky/insights-client-next/internal/impl"
 	"github.com/m-horky/insights-client-next/modules"
@@ -30,7 +35,6 @@ import (
 func init() {
 	initLogging()
 	initCLI()
-	initServices()
 }
 
 func initLogging() {
@@ -68,24 +72,120 @@ func initCLI() {
 	cli.VersionPrinter = func(cmd *cli.Command) {
 		fmt.Println("insights-client", internal.Version)
 		for _, module := range modules.GetModules() {
+			if err := module.Describe(); err != nil {
+				slog.Warn("could not describe module", slog.String("name", module.Name), slog.String("error", err.Error()))
+			}
 			fmt.Printf("* %s %s\n", module.Name, module.Version)
 		}
 	}
 }
 
-func initServices() {
+// initServices configures the API services from the configuration.
+//
+// The services are configured even when the authentication cannot be set up, the error is returned
+// to be reported by commands that talk to the API.
+func initServices() internal.IError {
 	config := internal.GetConfiguration()
+	authenticator, err := newAuthenticator(config)
+	if err != nil {
+		slog.Warn("could not configure authentication", slog.String("error", err.Error()))
+	}
 	// FIXME This won't work for IPv6 address
 	address := &url.URL{Scheme: config.APIProtocol, Host: fmt.Sprintf("%s:%d", config.APIHost, config.APIPort)}
-	// TODO Support the configuration file
-	template := api.NewService(address).WithAuthentication(config.IdentityCertificate, config.IdentityKey).WithProxy(os.Getenv("HTTP_PROXY"))
+	proxy := config.Proxy
+	if proxy == "" {
+		proxy = os.Getenv("HTTP_PROXY")
+	}
+	template := api.NewService(address).
+		WithAuthenticator(authenticator).
+		WithCA(config.CACertificate).
+		WithProxy(proxy).
+		WithTimeout(config.HTTPTimeout).
+		WithSatellite(config.Satellite)
 	inventory.Init(template)
 	ingress.Init(template)
+	advisor.Init(template)
+	remediations.Init(template)
+	compliance.Init(template)
+	rules.Init(template)
+	updates.Init(template)
+	return err
+}
+
+// warnConfiguration reports configuration values that are ignored, e.g. keys of the legacy client
+// that are not supported anymore.
+func warnConfiguration() {
+	for _, issue := range internal.CheckConfiguration() {
+		if issue.Severity == internal.SeverityError {
+			_, _ = fmt.Fprintf(os.Stderr, "Warning: %s\n", issue.String())
+		}
+	}
+}
+
+// newAuthenticator creates the authenticator selected by the `authmethod` configuration key.
+func newAuthenticator(config internal.Configuration) (api.Authenticator, internal.IError) {
+	switch config.AuthMethod {
+	case "basic":
+		if config.Username == "" {
+			return nil, internal.NewError(internal.ErrConfiguration, nil, "Configuration key 'username' is required by 'authmethod=basic'.")
+		}
+		secrets, err := internal.ReadSecrets()
+		if err != nil {
+			return nil, err
+		}
+		return &api.BasicAuthenticator{Username: config.Username, Password: secrets.Password}, nil
+	case "token":
+		if config.TokenURL == "" || config.TokenClientID == "" {
+			return nil, internal.NewError(internal.ErrConfiguration, nil, "Configuration keys 'token_url' and 'token_client_id' are required by 'authmethod=token'.")
+		}
+		secrets, err := internal.ReadSecrets()
+		if err != nil {
+			return nil, err
+		}
+		return &api.TokenAuthenticator{
+			TokenURL:     config.TokenURL,
+			ClientID:     config.TokenClientID,
+			ClientSecret: secrets.TokenClientSecret,
+			Scopes:       config.TokenScopes,
+		}, nil
+	default:
+		return &api.CertificateAuthenticator{Certificate: config.IdentityCertificate, Key: config.IdentityKey}, nil
+	}
+}
+
+// checkIdentity refuses to continue with an identity certificate that cannot be used.
+//
+// It warns when the certificate is about to expire.
+func checkIdentity() internal.IError {
+	config := internal.GetConfiguration()
+	if config.AuthMethod != "cert" {
+		return nil
+	}
+	certificate, err := api.InspectCertificate(config.IdentityCertificate, config.IdentityKey)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err = certificate.Validate(now); err != nil {
+		return err
+	}
+	if certificate.ExpiresWithin(now, time.Duration(config.CertificateWarningDays)*24*time.Hour) {
+		slog.Warn("identity certificate expires soon", slog.Time("expiry", certificate.NotAfter))
+		_, _ = fmt.Fprintf(
+			os.Stderr, "Warning: Identity certificate '%s' expires on %s.\n",
+			certificate.Path, certificate.NotAfter.Format(time.DateOnly),
+		)
+	}
+	return nil
 }
 
 func main() {
 	cmd := buildCLI()
 	cmd.CustomRootCommandHelpTemplate = buildHelpText()
+	if slices.Contains(os.Args[1:], "--help") {
+		// Modules have to be executed to describe their flags, only do it when necessary.
+		cmd.CustomRootCommandHelpTemplate += buildModuleHelpText()
+	}
 
 	slog.Debug("started", slog.Any("args", os.Args))
 	if err := cmd.Run(context.Background(), os.Args); err != nil {
@@ -120,21 +220,35 @@ var cliRootFlags = []Flag{
 	{"INVENTORY", 's', "display-name", "set display name of a host", []string{}},
 	{"INVENTORY", 's', "ansible-host", "set Ansible display name of a host", []string{}},
 	{"INVENTORY", 's', "group", "add system to Inventory group", []string{}},
+	{"INVENTORY", 'l', "set-host-fields", "set host field 'FIELD=VALUE' in Inventory (repeatable)", []string{}},
+	{"INVENTORY", 'b', "system-profile", "display system profile of a host", []string{}},
+	{"INVENTORY", 's', "facts", "display custom facts in a namespace", []string{}},
+	{"INVENTORY", 'l', "set-fact", "set custom fact 'KEY=VALUE' in a namespace (repeatable)", []string{}},
 	{"COLLECTION", 's', "output-dir", "do not upload, collect into directory", []string{}},
 	{"COLLECTION", 's', "output-file", "do not upload, collect into file", []string{}},
 	{"COLLECTION", 's', "payload", "upload archive from this path", []string{}},
 	{"COLLECTION", 's', "content-type", "upload archive with this content type", []string{}},
-	{"COLLECTION", 's', "collector", "run module collector", []string{"m"}},
+	{"COLLECTION", 'l', "collector", "run module collectors (comma-separated, repeatable, or 'all-enabled')", []string{"m"}},
 	{"COLLECTION", 'b', "check-results", "download Advisor report", []string{}},
 	{"COLLECTION", 'b', "show-results", "display Advisor report", []string{}},
+	{"COLLECTION", 's', "sort", "sort Advisor report by 'severity', 'category', 'rule' or 'date'", []string{}},
+	{"COLLECTION", 's', "severity", "only display Advisor report of this severity", []string{}},
+	{"COLLECTION", 's', "category", "only display Advisor report of this category", []string{}},
 	{"COLLECTION", 'b', "list-specs", "display Advisor collection specs", []string{}},
 	{"COLLECTION", 'b', "diagnosis", "display Remediations report", []string{}},
+	{"COLLECTION", 'b', "remediations", "display Remediations plans of a host", []string{}},
+	{"COLLECTION", 's', "playbook", "download playbook of Remediations plan", []string{}},
 	{"COLLECTION", 'b', "compliance", "run compliance", []string{}},
+	{"COLLECTION", 'b', "compliance-status", "display compliance policies and their status", []string{}},
 	{"COLLECTION", 'b', "no-upload", "alias for '--output-file [PATH]'", []string{}},
 	{"COLLECTION", 'b', "keep-archive", "alias for '--output-file [PATH]'", []string{}},
 	{"COLLECTION", 's', "manifest", "run Advisor with manifest", []string{}},
-	{"COLLECTION", 'b', "validate", "validate Advisor denylist", []string{}},
+	{"COLLECTION", 'b', "validate", "validate collection denylist", []string{}},
 	{"COLLECTION", 's', "build-packagecache", "refresh system package manager cache", []string{}},
+	{"CONFIGURATION", 'b', "config-show", "display effective configuration and its origin", []string{}},
+	{"CONFIGURATION", 's', "config-get", "display effective value of a configuration key", []string{}},
+	{"CONFIGURATION", 's', "config-set", "set configuration 'KEY=VALUE' in a drop-in file", []string{}},
+	{"CONFIGURATION", 'b', "config-check", "validate configuration files", []string{}},
 	{"GLOBAL", 's', "format", "change output format", []string{}},
 	{"GLOBAL", 'b', "debug", "print logs to stderr instead of a log file", []string{}},
 	{"GLOBAL", 'b', "offline", "for some commands, only do local changes", []string{}},
@@ -153,6 +267,28 @@ func buildHelpText() string {
 	// FIXME Can we make this not break in narrow terminals?
 	help := []string{`Usage: insights-client [COMMAND] [FLAGS]`}
 
+	maxCommandLength := 0
+	for _, command := range cliCommands {
+		if len(buildHelpCommand(command)) > maxCommandLength {
+			maxCommandLength = len(buildHelpCommand(command))
+		}
+	}
+	help = append(help, ``)
+	help = append(help, `Commands:`)
+	for _, command := range cliCommands {
+		if command.Parse == nil {
+			continue
+		}
+		help = append(help, fmt.Sprintf(
+			"  %s%s  %s",
+			buildHelpCommand(command),
+			strings.Repeat(" ", maxCommandLength-len(buildHelpCommand(command))),
+			command.Help,
+		))
+	}
+	help = append(help, ``)
+	help = append(help, `Flags below are deprecated in favor of commands, where a command exists.`)
+
 	maxFlagLength := 0
 	for _, flag := range cliRootFlags {
 		if len(buildHelpFlag(flag)) > maxFlagLength {
@@ -179,6 +315,43 @@ func buildHelpText() string {
 	return strings.Join(help, "\n") + "\n"
 }
 
+// buildModuleHelpText lists flags modules accept for their commands.
+func buildModuleHelpText() string {
+	var help []string
+	for _, module := range modules.GetModules() {
+		if err := module.Describe(); err != nil {
+			slog.Warn("could not describe module", slog.String("name", module.Name), slog.String("error", err.Error()))
+			continue
+		}
+		for _, command := range module.Commands {
+			if len(command.Flags) == 0 {
+				continue
+			}
+			help = append(help, ``)
+			help = append(help, fmt.Sprintf("Module command: %s (pass flags after '--')", strings.Join(command.Name, " ")))
+			for _, flag := range command.Flags {
+				name := buildHelpFlag(Flag{Name: flag.Name, Aliases: flag.Aliases})
+				if flag.Type != 'b' {
+					name += " VALUE"
+				}
+				help = append(help, fmt.Sprintf("  %s  %s", name, flag.Help))
+			}
+		}
+	}
+	if len(help) == 0 {
+		return ""
+	}
+	return strings.Join(help, "\n") + "\n"
+}
+
+// buildHelpCommand constructs a string out of the command and its arguments
+func buildHelpCommand(command Command) string {
+	if command.Args == "" {
+		return command.Name
+	}
+	return command.Name + " " + command.Args
+}
+
 // buildHelpFlag constructs a string out of the flag and its aliases
 func buildHelpFlag(flag Flag) string {
 	result := "--" + flag.Name
@@ -192,154 +365,179 @@ func buildHelpFlag(flag Flag) string {
 	return result
 }
 
-func buildCLI() *cli.Command {
+// cliGlobalFlags can be used with any flag or command.
+var cliGlobalFlags = []string{"format", "debug"}
+
+// buildFlags converts the proxy objects into cli.Flag objects.
+//
+// Global flags are passed down to commands.
+func buildFlags(flags []Flag) []cli.Flag {
 	var cliFlags []cli.Flag
-	for _, flag := range cliRootFlags {
+	for _, flag := range flags {
+		persistent := slices.Contains(cliGlobalFlags, flag.Name)
 		switch flag.Type {
 		case 'b':
-			cliFlags = append(cliFlags, &cli.BoolFlag{Name: flag.Name, Aliases: flag.Aliases})
+			cliFlags = append(cliFlags, &cli.BoolFlag{Name: flag.Name, Aliases: flag.Aliases, Usage: flag.Help, Persistent: persistent})
 		case 's':
-			cliFlags = append(cliFlags, &cli.StringFlag{Name: flag.Name, Aliases: flag.Aliases})
+			cliFlags = append(cliFlags, &cli.StringFlag{Name: flag.Name, Aliases: flag.Aliases, Usage: flag.Help, Persistent: persistent})
 		case 'l':
-			cliFlags = append(cliFlags, &cli.StringSliceFlag{Name: flag.Name, Aliases: flag.Aliases})
+			cliFlags = append(cliFlags, &cli.StringSliceFlag{Name: flag.Name, Aliases: flag.Aliases, Usage: flag.Help, Persistent: persistent})
 		default:
 			panic(fmt.Sprintf("Unsupported flag type: %v", flag.Type))
 		}
 	}
+	return cliFlags
+}
 
+func buildCLI() *cli.Command {
 	return &cli.Command{
 		Name:            "insights-client",
 		HideHelpCommand: true,
 		Version:         internal.Version,
 		Usage:           "Upload data to Red Hat Insights",
-		UsageText:       fmt.Sprintf("%s COMMAND [FLAGS...]", "insights-client"),
-		Flags:           cliFlags,
-		Action:          runCLI,
+		UsageText:       fmt.Sprintf("%s COMMAND [FLAGS...] [-- MODULE FLAGS...]", "insights-client"),
+		Flags:           buildFlags(cliRootFlags),
+		Commands: append(buildCommands(), &cli.Command{
+			Name:   "flag-combinations",
+			Usage:  "display valid combinations of flags",
+			Hidden: true,
+			Action: runFlagCombinations,
+		}),
+		Action:                runCLI,
+		Suggest:               true,
+		EnableShellCompletion: true,
+		ShellComplete:         completeCLI,
+		// Values of list flags may contain commas, e.g. '--set-fact note=a,b'.
+		// The flags are repeated instead; collectors are split in parseCollectors.
+		DisableSliceFlagSeparator: true,
 	}
 }
 
-// validateCLI performs input validation.
-//
-// It shows notices for cliRootFlags that are deprecated.
+// cliNoopFlags are accepted for compatibility, but they do not do anything.
+var cliNoopFlags = []string{"quiet", "retry", "silent", "conf", "compressor", "logging-file", "net-debug"}
+
+// cliFlagCombinations includes the list of all valid combinations of cliRootFlags.
 //
-// It ensures cliRootFlags that assume other cliRootFlags are properly joined.
-func validateCLI(cmd *cli.Command) internal.IError {
-	globalFlags := []string{"format", "debug"}
-	noopFlags := []string{"quiet", "retry", "silent", "conf", "compressor", "logging-file", "net-debug"}
-
-	// this includes the list of all valid combinations
-	flagCombinations := [][]string{
-		// HOST
-		{"register"},
-		{"register", "display-name"},
-		{"register", "ansible-host"},
-		{"register", "display-name", "ansible-host"},
-		{"register", "group"},
-		{"register", "group", "display-name"},
-		{"register", "group", "ansible-host"},
-		{"register", "group", "display-name", "ansible-host"},
-		{"unregister"},
-		{"status"},
-		{"checkin"},
-		{"test-connection"},
-		{"support"},
-		// INVENTORY
-		{"display-name"},
-		{"ansible-host"},
-		{"group"},
-		{"group", "offline"},
-		// COLLECTION
-		{"payload", "content-type"},
-		{"output-dir"},
-		{"output-file"},
-		{"collector"},
-		{"collector", "output-dir"},
-		{"collector", "output-file"},
-		{"collector", "no-upload"},
-		{"collector", "keep-archive"},
-		{"collector", "offline"},
-		{"compliance"},
-		{"compliance", "output-dir"},
-		{"compliance", "output-file"},
-		{"compliance", "no-upload"},
-		{"compliance", "keep-archive"},
-		{"compliance", "offline"},
-		{"offline"},
-		{"check-results"},
-		{"show-results"},
-		{"list-specs"},
-		{"diagnosis"},
-		{"no-upload"},
-		{"keep-archive"},
-		{"manifest"},
-		{"build-packagecache"},
-		// DEPRECATED
-		{"validate"},
-		{"enable-schedule"},
-		{"disable-schedule"},
-	}
-
-	setFlags := make(map[string]bool)
+// Global and no-op flags can be added to any of them.
+// The list is also used for suggestions, shell completion and documentation,
+// see the hidden `flag-combinations` command.
+var cliFlagCombinations = [][]string{
+	// HOST
+	{"register"},
+	{"register", "display-name"},
+	{"register", "ansible-host"},
+	{"register", "display-name", "ansible-host"},
+	{"register", "group"},
+	{"register", "group", "display-name"},
+	{"register", "group", "ansible-host"},
+	{"register", "group", "display-name", "ansible-host"},
+	{"unregister"},
+	{"status"},
+	{"checkin"},
+	{"test-connection"},
+	{"support"},
+	// INVENTORY
+	{"display-name"},
+	{"ansible-host"},
+	{"display-name", "ansible-host"},
+	{"set-host-fields"},
+	{"group"},
+	{"group", "offline"},
+	{"system-profile"},
+	{"facts"},
+	{"facts", "set-fact"},
+	// COLLECTION
+	{"payload", "content-type"},
+	{"output-dir"},
+	{"output-file"},
+	{"collector"},
+	{"collector", "output-dir"},
+	{"collector", "output-file"},
+	{"collector", "no-upload"},
+	{"collector", "keep-archive"},
+	{"collector", "offline"},
+	{"compliance"},
+	{"compliance", "output-dir"},
+	{"compliance", "output-file"},
+	{"compliance", "no-upload"},
+	{"compliance", "keep-archive"},
+	{"compliance", "offline"},
+	{"compliance-status"},
+	{"offline"},
+	{"check-results"},
+	{"show-results"},
+	{"show-results", "sort"},
+	{"show-results", "severity"},
+	{"show-results", "category"},
+	{"show-results", "sort", "severity"},
+	{"show-results", "sort", "category"},
+	{"show-results", "severity", "category"},
+	{"show-results", "sort", "severity", "category"},
+	{"list-specs"},
+	{"diagnosis"},
+	{"remediations"},
+	{"playbook"},
+	{"playbook", "output-file"},
+	{"no-upload"},
+	{"keep-archive"},
+	{"manifest"},
+	{"build-packagecache"},
+	// CONFIGURATION
+	{"config-show"},
+	{"config-get"},
+	{"config-set"},
+	{"config-check"},
+	// DEPRECATED
+	{"validate"},
+	{"enable-schedule"},
+	{"disable-schedule"},
+}
 
+// getSetFlags returns cliRootFlags that were set, except for global and no-op flags.
+//
+// The flags are sorted.
+func getSetFlags(cmd *cli.Command) []string {
+	var setFlags []string
 	for _, flag := range cmd.Flags {
 		flagName := flag.Names()[0]
-
-		if cmd.IsSet(flagName) {
-			// we don't need to check global flags, they can be applied to everything
-			flagIsGlobal := false
-			for _, globalFlag := range globalFlags {
-				if flagName == globalFlag {
-					flagIsGlobal = true
-					break
-				}
-			}
-			for _, globalFlag := range noopFlags {
-				if flagName == globalFlag {
-					flagIsGlobal = true
-					break
-				}
-			}
-			if flagIsGlobal {
-				continue
-			}
-
-			setFlags[flagName] = true
+		if !cmd.IsSet(flagName) {
+			continue
+		}
+		// we don't need to check global flags, they can be applied to everything
+		if slices.Contains(cliGlobalFlags, flagName) || slices.Contains(cliNoopFlags, flagName) {
+			continue
 		}
+		setFlags = append(setFlags, flagName)
 	}
+	sort.Strings(setFlags)
+	return setFlags
+}
+
+// validateCLI performs input validation.
+//
+// It ensures cliRootFlags that assume other cliRootFlags are properly joined.
+// When they are not, it suggests the closest valid combinations.
+func validateCLI(cmd *cli.Command) internal.IError {
+	setFlags := getSetFlags(cmd)
 
 	// Exit immediately if no flags were entered. Global flags are not considered.
 	if len(setFlags) == 0 {
 		return nil
 	}
 	// Exit immediately if we find combination match: validation is complete.
-	var finalFlags []string
-	for flag := range setFlags {
-		finalFlags = append(finalFlags, flag)
-	}
-	sort.Strings(finalFlags)
-	for _, combination := range flagCombinations {
-		sort.Strings(combination)
-		if reflect.DeepEqual(combination, finalFlags) {
-			return nil
-		}
+	if isFlagCombination(setFlags) {
+		return nil
 	}
 
-	// TODO Display all flag combinations that use the entered flags
-	//  "Did you mean...?"
-
-	setFlagList := make([]string, 0)
-	for flag, _ := range setFlags {
-		setFlagList = append(setFlagList, flag)
-	}
 	return internal.NewError(
 		internal.ErrInput,
-		fmt.Errorf("bad flag combination: %s", strings.Join(setFlagList, ",")),
-		"This flag combination is not valid.",
+		fmt.Errorf("bad flag combination: %s", strings.Join(setFlags, ",")),
+		buildFlagCombinationHelp(setFlags),
 	)
 }
 
-// parseCLI converts the cli.Command object into a clean structure.
-func parseCLI(cmd *cli.Command) (*impl.Input, error) {
+// parseGlobalFlags creates the input out of flags shared by all commands.
+func parseGlobalFlags(cmd *cli.Command) (*impl.Input, error) {
 	input := &impl.Input{}
 
 	if cmd.IsSet("format") {
@@ -353,6 +551,15 @@ func parseCLI(cmd *cli.Command) (*impl.Input, error) {
 	}
 
 	input.Debug = cmd.IsSet("debug")
+	return input, nil
+}
+
+// parseCLI converts the cli.Command object into a clean structure.
+func parseCLI(cmd *cli.Command) (*impl.Input, error) {
+	input, err := parseGlobalFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
 
 	if cmd.IsSet("help") {
 		input.Action = impl.AHelp
@@ -384,6 +591,13 @@ func parseCLI(cmd *cli.Command) (*impl.Input, error) {
 	}
 
 	// inventory
+	if cmd.IsSet("display-name") && cmd.IsSet("ansible-host") && input.Action == impl.ANone {
+		input.Action = impl.ASetHostFields
+		input.Args = impl.ASetHostFieldsArgs{Fields: map[string]string{
+			"display_name": cmd.String("display-name"),
+			"ansible_host": cmd.String("ansible-host"),
+		}}
+	}
 	if cmd.IsSet("display-name") && input.Action == impl.ANone {
 		input.Action = impl.ASetDisplayName
 		input.Args = impl.ASetDisplayNameArgs{Name: cmd.String("display-name")}
@@ -397,6 +611,33 @@ func parseCLI(cmd *cli.Command) (*impl.Input, error) {
 		input.Action = impl.ASetGroupLocally
 		input.Args = impl.ASetGroupLocallyArgs{Name: cmd.String("group")}
 	}
+	if cmd.IsSet("set-host-fields") && input.Action == impl.ANone {
+		fields := make(map[string]string)
+		for _, field := range cmd.StringSlice("set-host-fields") {
+			key, value, found := strings.Cut(field, "=")
+			if !found || key == "" {
+				return nil, internal.NewError(internal.ErrInput, nil, fmt.Sprintf("Host field '%s' is not in 'FIELD=VALUE' format.", field))
+			}
+			fields[key] = value
+		}
+		input.Action = impl.ASetHostFields
+		input.Args = impl.ASetHostFieldsArgs{Fields: fields}
+	}
+	if cmd.IsSet("system-profile") && input.Action == impl.ANone {
+		input.Action = impl.AShowSystemProfile
+	}
+	if cmd.IsSet("facts") && cmd.IsSet("set-fact") && input.Action == impl.ANone {
+		facts, err := parseFacts(cmd.StringSlice("set-fact"))
+		if err != nil {
+			return nil, err
+		}
+		input.Action = impl.ASetFacts
+		input.Args = impl.ASetFactsArgs{Namespace: cmd.String("facts"), Facts: facts}
+	}
+	if cmd.IsSet("facts") && input.Action == impl.ANone {
+		input.Action = impl.AShowFacts
+		input.Args = impl.AShowFactsArgs{Namespace: cmd.String("facts")}
+	}
 	if cmd.IsSet("group") && input.Action == impl.ANone {
 		// TODO Should we check that it is not not empty string?
 		input.Action = impl.ARunModule
@@ -415,33 +656,51 @@ func parseCLI(cmd *cli.Command) (*impl.Input, error) {
 		}
 	}
 	if cmd.IsSet("collector") && input.Action == impl.ANone {
-		switch cmd.String("collector") {
-		case "malware-detection":
+		collectors, err := parseCollectors(cmd.StringSlice("collector"))
+		if err != nil {
+			return nil, err
+		}
+		if len(collectors) == 1 {
 			input.Action = impl.ARunModule
-			input.Args = impl.ARunModuleArgs{Command: modules.GetMalwareModule().ArchiveCommandName}
-		default:
-			return nil, internal.NewError(nil, nil, fmt.Sprintf("Collector not known: '%s'.", cmd.String("collector")))
+			input.Args = collectors[0]
+		} else {
+			input.Action = impl.ARunModules
+			input.Args = impl.ARunModulesArgs{Modules: collectors}
 		}
 	}
 	if cmd.IsSet("compliance") && input.Action == impl.ANone {
 		input.Action = impl.ARunModule
 		input.Args = impl.ARunModuleArgs{Command: modules.GetComplianceModule().ArchiveCommandName}
 	}
+	if cmd.IsSet("compliance-status") && input.Action == impl.ANone {
+		input.Action = impl.AComplianceStatus
+	}
 	if cmd.IsSet("check-results") && input.Action == impl.ANone {
-		input.Action = impl.ARunModule
-		input.Args = impl.ARunModuleArgs{Command: []string{"advisor", "check-results"}}
+		input.Action = impl.ACheckResults
 	}
 	if cmd.IsSet("show-results") && input.Action == impl.ANone {
-		input.Action = impl.ARunModule
-		input.Args = impl.ARunModuleArgs{Command: []string{"advisor", "show-results"}}
+		input.Action = impl.AShowResults
+		input.Args = impl.AShowResultsArgs{
+			SortBy:   cmd.String("sort"),
+			Severity: cmd.String("severity"),
+			Category: cmd.String("category"),
+		}
 	}
 	if cmd.IsSet("list-specs") && input.Action == impl.ANone {
-		input.Action = impl.ARunModule
-		input.Args = impl.ARunModuleArgs{Command: []string{"advisor", "list-specs"}}
+		input.Action = impl.AListSpecs
 	}
 	if cmd.IsSet("diagnosis") && input.Action == impl.ANone {
-		input.Action = impl.ARunModule
-		input.Args = impl.ARunModuleArgs{Command: []string{"advisor", "diagnosis"}}
+		input.Action = impl.ADiagnosis
+	}
+	if cmd.IsSet("remediations") && input.Action == impl.ANone {
+		input.Action = impl.AListRemediations
+	}
+	if cmd.IsSet("playbook") && input.Action == impl.ANone {
+		input.Action = impl.ADownloadPlaybook
+		input.Args = impl.ADownloadPlaybookArgs{
+			RemediationID: cmd.String("playbook"),
+			Path:          cmd.String("output-file"),
+		}
 	}
 	if cmd.IsSet("manifest") && input.Action == impl.ANone {
 		input.Action = impl.ARunModule
@@ -452,8 +711,27 @@ func parseCLI(cmd *cli.Command) (*impl.Input, error) {
 		input.Args = impl.ARunModuleArgs{Command: []string{"advisor", "build-packagecache"}}
 	}
 	if cmd.IsSet("validate") && input.Action == impl.ANone {
-		input.Action = impl.ARunModule
-		input.Args = impl.ARunModuleArgs{Command: []string{"advisor", "validate"}}
+		input.Action = impl.AValidate
+	}
+
+	// configuration
+	if cmd.IsSet("config-show") && input.Action == impl.ANone {
+		input.Action = impl.AConfigShow
+	}
+	if cmd.IsSet("config-get") && input.Action == impl.ANone {
+		input.Action = impl.AConfigGet
+		input.Args = impl.AConfigGetArgs{Key: cmd.String("config-get")}
+	}
+	if cmd.IsSet("config-set") && input.Action == impl.ANone {
+		key, value, found := strings.Cut(cmd.String("config-set"), "=")
+		if !found || key == "" {
+			return nil, internal.NewError(internal.ErrInput, nil, fmt.Sprintf("Configuration '%s' is not in 'KEY=VALUE' format.", cmd.String("config-set")))
+		}
+		input.Action = impl.AConfigSet
+		input.Args = impl.AConfigSetArgs{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value)}
+	}
+	if cmd.IsSet("config-check") && input.Action == impl.ANone {
+		input.Action = impl.AConfigCheck
 	}
 
 	// default action
@@ -462,44 +740,137 @@ func parseCLI(cmd *cli.Command) (*impl.Input, error) {
 		input.Args = impl.ARunModuleArgs{Command: modules.GetAdvisorModule().ArchiveCommandName}
 	}
 
-	// module flags
+	if moduleErr := parseModuleFlags(cmd, input); moduleErr != nil {
+		return nil, moduleErr
+	}
+	return input, nil
+}
+
+// parseFacts converts 'KEY=VALUE' strings into custom facts.
+func parseFacts(values []string) (map[string]string, internal.IError) {
+	facts := make(map[string]string)
+	for _, fact := range values {
+		key, value, found := strings.Cut(fact, "=")
+		if !found || key == "" {
+			return nil, internal.NewError(internal.ErrInput, nil, fmt.Sprintf("Fact '%s' is not in 'KEY=VALUE' format.", fact))
+		}
+		facts[key] = value
+	}
+	return facts, nil
+}
+
+// parseModuleFlags adds flags after '--' and archive flags to module commands.
+func parseModuleFlags(cmd *cli.Command, input *impl.Input) internal.IError {
 	if input.Action == impl.ARunModule {
 		args := input.Args.(impl.ARunModuleArgs)
-		if !modules.CommandExists(args.Command) {
-			return nil, internal.NewError(nil, nil, fmt.Sprintf("No module implements command '%s'.", strings.Join(args.Command, " ")))
-		}
-
-		// We have to figure out what to do based on the following options:
-		// --no-upload
-		// --keep-archive
-		// --offline
-		// --output-dir
-		// --output-file
-		if cmd.IsSet("output-dir") {
-			args.ArchiveParent = cmd.String("output-dir")
-			args.ArchiveName = fmt.Sprintf("archive-%d", time.Now().Unix())
-			args.StopAtDir = true
-		} else if cmd.IsSet("output-file") {
-			args.ArchiveParent = filepath.Dir(cmd.String("output-file"))
-			args.ArchiveName = strings.Split(filepath.Base(cmd.String("output-file")), ".")[0]
-			args.StopAtFile = true
-		} else if cmd.IsSet("no-upload") {
-			args.ArchiveParent = internal.ArchiveDirectoryParentPath
-			args.ArchiveName = fmt.Sprintf("archive-%d", time.Now().Unix())
-			args.StopAtFile = true
-		} else if cmd.IsSet("offline") {
-			args.ArchiveParent = internal.ArchiveDirectoryParentPath
-			args.ArchiveName = fmt.Sprintf("archive-%d", time.Now().Unix())
-			args.StopAtFile = true
-		} else if cmd.IsSet("keep-archive") {
-			args.ArchiveParent = internal.ArchiveDirectoryParentPath
-			args.ArchiveName = fmt.Sprintf("archive-%d", time.Now().Unix())
-			args.StopAtCleanup = true
+		module, ok := modules.GetModuleByCommand(args.Command)
+		if !ok {
+			return internal.NewError(nil, nil, fmt.Sprintf("No module implements command '%s'.", strings.Join(args.Command, " ")))
+		}
+
+		// Flags after '--' are passed to the module as they are.
+		if cmd.Args().Present() {
+			if err := module.Describe(); err != nil {
+				return err
+			}
+			if err := module.ValidateOptions(args.Command, cmd.Args().Slice()); err != nil {
+				return err
+			}
+			args.Options = append(args.Options, cmd.Args().Slice()...)
+		}
+
+		input.Args = parseArchiveFlags(cmd, args)
+	}
+	if input.Action == impl.ARunModules {
+		args := input.Args.(impl.ARunModulesArgs)
+		if cmd.Args().Present() {
+			return internal.NewError(internal.ErrInput, nil, "Module flags can only be passed to a single collector.")
+		}
+		if cmd.IsSet("output-file") {
+			return internal.NewError(internal.ErrInput, nil, "Output file can only be used with a single collector.")
+		}
+		for i := range args.Modules {
+			args.Modules[i] = parseArchiveFlags(cmd, args.Modules[i])
+			// Each module is collected into its own archive.
+			args.Modules[i].ArchiveName = ""
 		}
 		input.Args = args
 	}
+	return nil
+}
 
-	return input, nil
+// parseArchiveFlags sets up what should happen to the collected data.
+func parseArchiveFlags(cmd *cli.Command, args impl.ARunModuleArgs) impl.ARunModuleArgs {
+	// We have to figure out what to do based on the following options:
+	// --no-upload
+	// --keep-archive
+	// --offline
+	// --output-dir
+	// --output-file
+	if cmd.IsSet("output-dir") {
+		args.ArchiveParent = cmd.String("output-dir")
+		args.ArchiveName = fmt.Sprintf("archive-%d", time.Now().Unix())
+		args.StopAtDir = true
+	} else if cmd.IsSet("output-file") {
+		args.ArchiveParent = filepath.Dir(cmd.String("output-file"))
+		args.ArchiveName = strings.Split(filepath.Base(cmd.String("output-file")), ".")[0]
+		args.StopAtFile = true
+	} else if cmd.IsSet("no-upload") {
+		args.ArchiveParent = internal.ArchiveDirectoryParentPath
+		args.ArchiveName = fmt.Sprintf("archive-%d", time.Now().Unix())
+		args.StopAtFile = true
+	} else if cmd.IsSet("offline") {
+		args.ArchiveParent = internal.ArchiveDirectoryParentPath
+		args.ArchiveName = fmt.Sprintf("archive-%d", time.Now().Unix())
+		args.StopAtFile = true
+		args.Offline = true
+	} else if cmd.IsSet("keep-archive") {
+		args.ArchiveParent = internal.ArchiveDirectoryParentPath
+		args.ArchiveName = fmt.Sprintf("archive-%d", time.Now().Unix())
+		args.StopAtCleanup = true
+	}
+	return args
+}
+
+// parseCollectors resolves collector names into collection commands.
+//
+// Each value is a comma-separated list of names; 'all-enabled' is expanded into modules enabled
+// in the configuration.
+func parseCollectors(values []string) ([]impl.ARunModuleArgs, internal.IError) {
+	var names []string
+	for _, value := range values {
+		names = append(names, strings.Split(value, ",")...)
+	}
+
+	var expanded []string
+	for _, name := range names {
+		if name == "all-enabled" {
+			expanded = append(expanded, internal.GetConfiguration().EnabledModules...)
+		} else {
+			expanded = append(expanded, name)
+		}
+	}
+
+	var collectors []impl.ARunModuleArgs
+	seen := make(map[string]bool)
+	for _, name := range expanded {
+		module, err := modules.GetModule(name)
+		if err != nil {
+			return nil, internal.NewError(internal.ErrInput, err, fmt.Sprintf("Collector not known: '%s'.", name))
+		}
+		if len(module.ArchiveCommandName) == 0 {
+			return nil, internal.NewError(internal.ErrInput, nil, fmt.Sprintf("Collector '%s' cannot collect data.", module.Name))
+		}
+		if seen[module.Name] {
+			continue
+		}
+		seen[module.Name] = true
+		collectors = append(collectors, impl.ARunModuleArgs{Command: module.ArchiveCommandName})
+	}
+	if len(collectors) == 0 {
+		return nil, internal.NewError(internal.ErrInput, nil, "No collector is enabled.")
+	}
+	return collectors, nil
 }
 
 func runCLI(_ context.Context, cmd *cli.Command) error {
@@ -511,17 +882,49 @@ func runCLI(_ context.Context, cmd *cli.Command) error {
 	if err != nil {
 		return err
 	}
+	if hint := buildLegacyHint(cmd); hint != "" {
+		slog.Debug("legacy flag used", slog.String("hint", hint))
+		_, _ = fmt.Fprintln(os.Stderr, "Hint: "+hint)
+	}
 
+	return runInput(cmd, input)
+}
+
+// runInput performs the action requested by flags or by a command.
+func runInput(cmd *cli.Command, input *impl.Input) error {
 	if input.Action == impl.AHelp {
 		_ = cli.ShowAppHelp(cmd)
 		return nil
 	}
+	// configuration files are world-readable
+	if input.Action == impl.AConfigShow {
+		return impl.RunConfigShow(input)
+	}
+	if input.Action == impl.AConfigGet {
+		return impl.RunConfigGet(input)
+	}
+	if input.Action == impl.AConfigCheck {
+		return impl.RunConfigCheck(input)
+	}
+	warnConfiguration()
 
 	// ask for elevated privileges
 	if os.Geteuid() != 0 {
 		return internal.NewError(internal.ErrPermissions, nil, "This command has to be run with superuser privileges.")
 	}
 
+	// actions that do not talk to the API work even when the services are broken,
+	// e.g. the configuration can be fixed
+	if err := initServices(); err != nil && input.UsesAPI() {
+		return err
+	}
+	// the status displays problems of the identity on its own
+	if input.UsesAPI() && input.Action != impl.AStatus {
+		if err := checkIdentity(); err != nil {
+			return err
+		}
+	}
+
 	switch input.Action {
 	case impl.ARegister:
 		return impl.RunRegister(input)
@@ -537,6 +940,12 @@ func runCLI(_ context.Context, cmd *cli.Command) error {
 		return impl.RunSetAnsibleHostname(input)
 	case impl.ARunModule:
 		return impl.RunModule(input)
+	case impl.ARunModules:
+		return impl.RunModules(input)
+	case impl.AListSpecs:
+		return impl.RunListSpecs(input)
+	case impl.AValidate:
+		return impl.RunValidate(input)
 	case impl.AUploadLocalArchive:
 		return impl.RunUploadLocalArchive(input)
 	case impl.ATestConnection:
@@ -545,6 +954,28 @@ func runCLI(_ context.Context, cmd *cli.Command) error {
 		return impl.RunSupport(input)
 	case impl.ASetGroupLocally:
 		return impl.RunSetGroupLocally(input)
+	case impl.ACheckResults:
+		return impl.RunCheckResults(input)
+	case impl.AShowResults:
+		return impl.RunShowResults(input)
+	case impl.ADiagnosis:
+		return impl.RunDiagnosis(input)
+	case impl.AListRemediations:
+		return impl.RunListRemediations(input)
+	case impl.ADownloadPlaybook:
+		return impl.RunDownloadPlaybook(input)
+	case impl.AComplianceStatus:
+		return impl.RunComplianceStatus(input)
+	case impl.ASetHostFields:
+		return impl.RunSetHostFields(input)
+	case impl.AShowSystemProfile:
+		return impl.RunShowSystemProfile(input)
+	case impl.AShowFacts:
+		return impl.RunShowFacts(input)
+	case impl.ASetFacts:
+		return impl.RunSetFacts(input)
+	case impl.AConfigSet:
+		return impl.RunConfigSet(input)
 	default:
 		return internal.NewError(internal.ErrInput, fmt.Errorf("bad input: %#v", input), "Not implemented.")
 	}
@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -11,8 +13,21 @@ import (
 	"github.com/urfave/cli/v3"
 
 	"github.com/m-horky/insights-client-next/internal/impl"
+	"github.com/m-horky/insights-client-next/modules"
 )
 
+// useModuleFixtures reads module manifests from testdata instead of the directories of the host.
+func useModuleFixtures(t *testing.T) {
+	directories, owner := modules.ModuleDirectories, modules.ModuleDirectoryOwner
+	t.Cleanup(func() {
+		modules.ModuleDirectories, modules.ModuleDirectoryOwner = directories, owner
+		modules.ClearModules()
+	})
+	modules.ModuleDirectoryOwner = os.Geteuid()
+	modules.ModuleDirectories = []string{"testdata/modules.d"}
+	modules.ClearModules()
+}
+
 // TestValidateCLI_valid ensures that some flags can be used together.
 //
 // This is NOT an exhaustive list.
@@ -30,15 +45,32 @@ func TestValidateCLI_valid(t *testing.T) {
 		{[]string{"--output-file", "x"}},
 		{[]string{"--output-dir", "x"}},
 		{[]string{"--checkin"}},
+		{[]string{"--display-name", "x", "--ansible-host", "x"}},
+		{[]string{"--set-host-fields", "display_name=x", "--set-host-fields", "ansible_host=y"}},
+		{[]string{"--system-profile"}},
+		{[]string{"--facts", "x"}},
+		{[]string{"--facts", "x", "--set-fact", "a=b", "--set-fact", "c=d"}},
 		{[]string{"--payload", "x", "--content-type", "x"}},
+		{[]string{"--check-results"}},
+		{[]string{"--diagnosis"}},
+		{[]string{"--remediations"}},
+		{[]string{"--playbook", "x", "--output-file", "x"}},
+		{[]string{"--show-results", "--sort", "category", "--severity", "critical"}},
 		{[]string{"--compliance"}},
 		{[]string{"--compliance", "--no-upload"}},
+		{[]string{"--compliance-status"}},
 		{[]string{"--collector", "x"}},
 		{[]string{"-m", "x"}},
 		{[]string{"-m", "x", "--no-upload"}},
 		{[]string{"-m", "x", "--keep-archive"}},
 		{[]string{"-m", "x", "--output-file", "x"}},
 		{[]string{"-m", "x", "--output-dir", "x"}},
+		{[]string{"-m", "x,y", "--no-upload"}},
+		{[]string{"-m", "x", "-m", "y", "--keep-archive"}},
+		{[]string{"--config-show"}},
+		{[]string{"--config-get", "loglevel"}},
+		{[]string{"--config-set", "loglevel=info"}},
+		{[]string{"--config-check"}},
 	}
 
 	for _, test := range tests {
@@ -75,6 +107,14 @@ func TestValidateCLI_invalid(t *testing.T) {
 		{[]string{"--payload", "x", "--offline"}},
 		{[]string{"--payload", "x", "--output-dir", "x"}},
 		{[]string{"-m", "x", "--display-name", "x"}},
+		{[]string{"--set-fact", "a=b"}},
+		{[]string{"--set-host-fields", "display_name=x", "--display-name", "x"}},
+		{[]string{"--system-profile", "--facts", "x"}},
+		{[]string{"--check-results", "--sort", "rule"}},
+		{[]string{"--playbook", "x", "--output-dir", "x"}},
+		{[]string{"--compliance-status", "--no-upload"}},
+		{[]string{"--config-show", "--config-get", "loglevel"}},
+		{[]string{"--config-set", "loglevel=info", "--offline"}},
 	}
 
 	for _, test := range tests {
@@ -98,6 +138,7 @@ func TestValidateCLI_invalid(t *testing.T) {
 }
 
 func TestParseCLI(t *testing.T) {
+	useModuleFixtures(t)
 	tests := []struct {
 		Input  []string
 		Action impl.InputAction
@@ -116,6 +157,25 @@ func TestParseCLI(t *testing.T) {
 		{[]string{"--display-name", "x"}, impl.ASetDisplayName, impl.ASetDisplayNameArgs{Name: "x"}},
 		{[]string{"--ansible-host", "x"}, impl.ASetAnsibleHostname, impl.ASetAnsibleHostnameArgs{Name: "x"}},
 		{[]string{"--group", "x", "--offline"}, impl.ASetGroupLocally, impl.ASetGroupLocallyArgs{Name: "x"}},
+		{[]string{"--display-name", "x", "--ansible-host", "y"}, impl.ASetHostFields, impl.ASetHostFieldsArgs{
+			Fields: map[string]string{"display_name": "x", "ansible_host": "y"},
+		}},
+		{[]string{"--set-host-fields", "display_name=x", "--set-host-fields", "ansible_host=y"}, impl.ASetHostFields, impl.ASetHostFieldsArgs{
+			Fields: map[string]string{"display_name": "x", "ansible_host": "y"},
+		}},
+		{[]string{"--set-host-fields", "display_name=x,y"}, impl.ASetHostFields, impl.ASetHostFieldsArgs{
+			Fields: map[string]string{"display_name": "x,y"},
+		}},
+		{[]string{"--system-profile"}, impl.AShowSystemProfile, nil},
+		{[]string{"--facts", "x"}, impl.AShowFacts, impl.AShowFactsArgs{Namespace: "x"}},
+		{[]string{"--facts", "x", "--set-fact", "a=b", "--set-fact", "c=d=e"}, impl.ASetFacts, impl.ASetFactsArgs{
+			Namespace: "x",
+			Facts:     map[string]string{"a": "b", "c": "d=e"},
+		}},
+		{[]string{"--facts", "x", "--set-fact", "a=b,c"}, impl.ASetFacts, impl.ASetFactsArgs{
+			Namespace: "x",
+			Facts:     map[string]string{"a": "b,c"},
+		}},
 		{[]string{"--group", "x"}, impl.ARunModule, impl.ARunModuleArgs{
 			Command:       []string{"advisor", "collect"},
 			Options:       []string{"--group", "x"},
@@ -153,12 +213,48 @@ func TestParseCLI(t *testing.T) {
 			StopAtFile:    false,
 			StopAtCleanup: true,
 		}},
+		{[]string{"--collector", "advisor,malware", "--no-upload"}, impl.ARunModules, impl.ARunModulesArgs{Modules: []impl.ARunModuleArgs{
+			{Command: []string{"advisor", "collect"}, ArchiveParent: "/var/cache/insights-client/", StopAtFile: true},
+			{Command: []string{"malware", "collect"}, ArchiveParent: "/var/cache/insights-client/", StopAtFile: true},
+		}}},
+		{[]string{"-m", "malware", "-m", "malware-detection"}, impl.ARunModule, impl.ARunModuleArgs{
+			Command: []string{"malware", "collect"},
+		}},
+		{[]string{"-m", "advisor,malware", "-m", "scanner"}, impl.ARunModules, impl.ARunModulesArgs{Modules: []impl.ARunModuleArgs{
+			{Command: []string{"advisor", "collect"}},
+			{Command: []string{"malware", "collect"}},
+			{Command: []string{"scanner", "collect"}},
+		}}},
+		{[]string{"--collector", "scanner"}, impl.ARunModule, impl.ARunModuleArgs{
+			Command: []string{"scanner", "collect"},
+		}},
+		{[]string{"--list-specs"}, impl.AListSpecs, nil},
+		{[]string{"--validate"}, impl.AValidate, nil},
+		{[]string{"--check-results"}, impl.ACheckResults, nil},
+		{[]string{"--show-results"}, impl.AShowResults, impl.AShowResultsArgs{}},
+		{[]string{"--show-results", "--severity", "low", "--category", "x", "--sort", "date"}, impl.AShowResults, impl.AShowResultsArgs{
+			SortBy:   "date",
+			Severity: "low",
+			Category: "x",
+		}},
+		{[]string{"--compliance-status"}, impl.AComplianceStatus, nil},
+		{[]string{"--diagnosis"}, impl.ADiagnosis, nil},
+		{[]string{"--remediations"}, impl.AListRemediations, nil},
+		{[]string{"--playbook", "x"}, impl.ADownloadPlaybook, impl.ADownloadPlaybookArgs{RemediationID: "x"}},
+		{[]string{"--playbook", "x", "--output-file", "y.yml"}, impl.ADownloadPlaybook, impl.ADownloadPlaybookArgs{
+			RemediationID: "x",
+			Path:          "y.yml",
+		}},
+		{[]string{"--config-show"}, impl.AConfigShow, nil},
+		{[]string{"--config-get", "loglevel"}, impl.AConfigGet, impl.AConfigGetArgs{Key: "loglevel"}},
+		{[]string{"--config-set", "module_env.advisor.A=b=c"}, impl.AConfigSet, impl.AConfigSetArgs{
+			Key:   "module_env.advisor.A",
+			Value: "b=c",
+		}},
+		{[]string{"--config-check"}, impl.AConfigCheck, nil},
 		// TODO Add more tests
 		// {[]string{"--manifest", "x"}, impl.ARunModule, impl.ARunModuleArgs{}},
 		// {[]string{"--build-packagecache"}, impl.ARunModule, impl.ARunModuleArgs{}},
-		// {[]string{"--list-specs"}, impl.ARunModule, impl.ARunModuleArgs{}},
-		// {[]string{"--validate"}, impl.ARunModule, impl.ARunModuleArgs{}},
-		// {[]string{"--diagnosis"}, impl.ARunModule, impl.ARunModuleArgs{}},
 	}
 
 	for _, test := range tests {
@@ -187,3 +283,208 @@ func TestParseCLI(t *testing.T) {
 		})
 	}
 }
+
+// setCommandAction replaces actions of all commands in the tree.
+func setCommandAction(commands []*cli.Command, action cli.ActionFunc) {
+	for _, command := range commands {
+		if command.Action != nil {
+			command.Action = action
+		}
+		setCommandAction(command.Commands, action)
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	useModuleFixtures(t)
+	tests := []struct {
+		Input  []string
+		Action impl.InputAction
+		Args   any
+	}{
+		{[]string{"host", "register"}, impl.ARegister, impl.ARegisterArgs{}},
+		{[]string{"host", "register", "--group", "x", "--display-name", "y"}, impl.ARegister, impl.ARegisterArgs{Group: "x", DisplayName: "y"}},
+		{[]string{"host", "unregister"}, impl.AUnregister, nil},
+		{[]string{"host", "status"}, impl.AStatus, nil},
+		{[]string{"--format", "json", "host", "status"}, impl.AStatus, nil},
+		{[]string{"host", "status", "--format", "json"}, impl.AStatus, nil},
+		{[]string{"inventory", "set-name", "--display-name", "x"}, impl.ASetDisplayName, impl.ASetDisplayNameArgs{Name: "x"}},
+		{[]string{"inventory", "set-name", "--ansible-host", "x"}, impl.ASetAnsibleHostname, impl.ASetAnsibleHostnameArgs{Name: "x"}},
+		{[]string{"inventory", "set-name", "--display-name", "x", "--ansible-host", "y"}, impl.ASetHostFields, impl.ASetHostFieldsArgs{
+			Fields: map[string]string{"display_name": "x", "ansible_host": "y"},
+		}},
+		{[]string{"inventory", "tags", "x"}, impl.AShowFacts, impl.AShowFactsArgs{Namespace: "x"}},
+		{[]string{"inventory", "tags", "x", "a=b", "c=d=e"}, impl.ASetFacts, impl.ASetFactsArgs{
+			Namespace: "x",
+			Facts:     map[string]string{"a": "b", "c": "d=e"},
+		}},
+		{[]string{"collect", "advisor"}, impl.ARunModule, impl.ARunModuleArgs{Command: []string{"advisor", "collect"}}},
+		{[]string{"collect", "compliance", "--keep-archive"}, impl.ARunModule, impl.ARunModuleArgs{
+			Command:       []string{"compliance", "collect"},
+			ArchiveParent: "/var/cache/insights-client/",
+			ArchiveName:   fmt.Sprintf("archive-%d", time.Now().Unix()),
+			StopAtCleanup: true,
+		}},
+		{[]string{"upload", "--content-type", "y", "x"}, impl.AUploadLocalArchive, impl.AUploadLocalArchiveArgs{Path: "x", ContentType: "y"}},
+		{[]string{"results", "--sort", "date"}, impl.AShowResults, impl.AShowResultsArgs{SortBy: "date"}},
+	}
+
+	for _, test := range tests {
+		t.Run(strings.Join(test.Input, " "), func(t *testing.T) {
+			input := buildCLI()
+			setCommandAction(input.Commands, func(_ context.Context, command *cli.Command) error {
+				parsed, err := parseCommand(command)
+				if err != nil {
+					t.Fatalf("expected 'nil', got '%v'", err)
+				}
+
+				if parsed.Action != test.Action {
+					t.Fatalf("expected '%v', got '%v'", test.Action, parsed.Action)
+				}
+
+				if !reflect.DeepEqual(test.Args, parsed.Args) {
+					t.Fatalf("expected '%+v', got '%+v'", test.Args, parsed.Args)
+				}
+				return nil
+			})
+
+			args := []string{input.Name}
+			args = append(args, test.Input...)
+			if err := input.Run(context.Background(), args); err != nil {
+				t.Fatalf("expected 'nil', got '%v'", err)
+			}
+		})
+	}
+}
+
+func TestParseCommand_invalid(t *testing.T) {
+	useModuleFixtures(t)
+	tests := []struct {
+		Input []string
+	}{
+		{[]string{"--register", "host", "status"}},
+		{[]string{"host", "status", "x"}},
+		{[]string{"inventory", "set-name"}},
+		{[]string{"inventory", "tags"}},
+		{[]string{"inventory", "tags", "x", "a"}},
+		{[]string{"collect", "advisor", "--no-upload", "--offline"}},
+		{[]string{"upload", "x"}},
+		{[]string{"upload", "--content-type", "y"}},
+	}
+
+	for _, test := range tests {
+		t.Run(strings.Join(test.Input, " "), func(t *testing.T) {
+			input := buildCLI()
+			setCommandAction(input.Commands, func(_ context.Context, command *cli.Command) error {
+				if _, err := parseCommand(command); err == nil {
+					t.Errorf("expected error, got 'nil'")
+				}
+				return nil
+			})
+
+			args := []string{input.Name}
+			args = append(args, test.Input...)
+			_ = input.Run(context.Background(), args)
+		})
+	}
+}
+
+// TestLegacyFlagCommands ensures legacy flags are mapped onto existing commands.
+func TestLegacyFlagCommands(t *testing.T) {
+	for _, legacy := range legacyFlagCommands {
+		t.Run(legacy.Flag, func(t *testing.T) {
+			if !slices.ContainsFunc(cliRootFlags, func(flag Flag) bool { return flag.Name == legacy.Flag }) {
+				t.Errorf("flag '%s' does not exist", legacy.Flag)
+			}
+			if !slices.ContainsFunc(cliCommands, func(command Command) bool {
+				return command.Name == legacy.Command && command.Parse != nil
+			}) {
+				t.Errorf("command '%s' does not exist", legacy.Command)
+			}
+		})
+	}
+}
+
+// TestFlagCombinations ensures combinations only consist of existing flags.
+func TestFlagCombinations(t *testing.T) {
+	for _, combination := range cliFlagCombinations {
+		for _, name := range combination {
+			if !slices.ContainsFunc(cliRootFlags, func(flag Flag) bool { return flag.Name == name }) {
+				t.Errorf("flag '%s' of combination %v does not exist", name, combination)
+			}
+		}
+	}
+}
+
+func TestSuggestFlagCombinations(t *testing.T) {
+	tests := []struct {
+		Input    []string
+		Expected [][]string
+	}{
+		{[]string{"register", "status"}, [][]string{{"register"}, {"status"}}},
+		{[]string{"set-fact"}, [][]string{{"facts", "set-fact"}}},
+		{[]string{"output-dir", "playbook"}, [][]string{{"output-dir"}, {"playbook"}, {"playbook", "output-file"}}},
+		{[]string{"category", "diagnosis", "show-results"}, [][]string{{"show-results", "category"}}},
+		{[]string{"retry"}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(strings.Join(test.Input, " "), func(t *testing.T) {
+			var suggested [][]string
+			for _, suggestion := range suggestFlagCombinations(test.Input) {
+				suggested = append(suggested, suggestion.Combination)
+			}
+			if !reflect.DeepEqual(test.Expected, suggested) {
+				t.Errorf("expected '%v', got '%v'", test.Expected, suggested)
+			}
+		})
+	}
+}
+
+func TestBuildFlagCombinationHelp(t *testing.T) {
+	tests := []struct {
+		Input    []string
+		Expected string
+	}{
+		{[]string{"register", "status"}, `This flag combination is not valid.
+Flag '--register' cannot be used with '--status'.
+Did you mean:
+  insights-client --register (remove '--status')
+  insights-client --status (remove '--register')`},
+		{[]string{"set-fact"}, `This flag combination is not valid.
+Did you mean:
+  insights-client --facts VALUE --set-fact VALUE (add '--facts')`},
+		{[]string{"category", "diagnosis", "show-results"}, `This flag combination is not valid.
+Flag '--category' cannot be used with '--diagnosis'.
+Flag '--diagnosis' cannot be used with '--show-results'.
+Did you mean:
+  insights-client --show-results --category VALUE (remove '--diagnosis')`},
+		{[]string{"retry"}, "This flag combination is not valid."},
+	}
+
+	for _, test := range tests {
+		t.Run(strings.Join(test.Input, " "), func(t *testing.T) {
+			if help := buildFlagCombinationHelp(test.Input); help != test.Expected {
+				t.Errorf("expected '%v', got '%v'", test.Expected, help)
+			}
+		})
+	}
+}
+
+func TestCompatibleFlags(t *testing.T) {
+	tests := []struct {
+		Input    []string
+		Expected []string
+	}{
+		{[]string{"register"}, []string{"display-name", "ansible-host", "group", "format", "debug"}},
+		{[]string{"facts"}, []string{"set-fact", "format", "debug"}},
+		{[]string{"playbook", "output-file"}, []string{"format", "debug"}},
+	}
+
+	for _, test := range tests {
+		t.Run(strings.Join(test.Input, " "), func(t *testing.T) {
+			if compatible := compatibleFlags(test.Input); !reflect.DeepEqual(test.Expected, compatible) {
+				t.Errorf("expected '%v', got '%v'", test.Expected, compatible)
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/m-horky/insights-client-next/internal"
+	"github.com/m-horky/insights-client-next/internal/impl"
+	"github.com/m-horky/insights-client-next/modules"
+)
+
+// Command is a proxy for cli.Command object.
+//
+// Commands are grouped by the first word of their name, e.g. `host register`.
+// Commands without Parse are groups.
+type Command struct {
+	Name  string
+	Help  string
+	Args  string
+	Flags []Flag
+	// Parse converts arguments and flags of the command into the action and its arguments.
+	Parse func(cmd *cli.Command, input *impl.Input) internal.IError
+}
+
+// archiveFlags decide what happens to the collected data.
+var archiveFlags = []Flag{
+	{"", 's', "output-dir", "do not upload, collect into directory", []string{}},
+	{"", 's', "output-file", "do not upload, collect into file", []string{}},
+	{"", 'b', "no-upload", "do not upload, keep the archive", []string{}},
+	{"", 'b', "keep-archive", "upload and keep the archive", []string{}},
+	{"", 'b', "offline", "do not upload, do not contact the API", []string{}},
+}
+
+// cliCommands defines all existing CLI commands.
+var cliCommands = []Command{
+	{Name: "host", Help: "manage registration of the host"},
+	{Name: "host register", Help: "register the host", Flags: []Flag{
+		{"", 's', "display-name", "set display name of a host", []string{}},
+		{"", 's', "ansible-host", "set Ansible display name of a host", []string{}},
+		{"", 's', "group", "add system to Inventory group", []string{}},
+	}, Parse: parseHostRegister},
+	{Name: "host unregister", Help: "unregister the host", Parse: parseAction(impl.AUnregister)},
+	{Name: "host status", Help: "display host status", Parse: parseAction(impl.AStatus)},
+	{Name: "host checkin", Help: "send lightweight check-in notification", Parse: parseAction(impl.ACheckIn)},
+	{Name: "host test-connection", Help: "test API connectivity", Parse: parseAction(impl.ATestConnection)},
+	{Name: "inventory", Help: "manage the host record in Inventory"},
+	{Name: "inventory set-name", Help: "set display names of a host", Flags: []Flag{
+		{"", 's', "display-name", "set display name of a host", []string{}},
+		{"", 's', "ansible-host", "set Ansible display name of a host", []string{}},
+	}, Parse: parseInventorySetName},
+	{Name: "inventory tags", Help: "display or set custom facts in a namespace", Args: "NAMESPACE [KEY=VALUE...]", Parse: parseInventoryTags},
+	{Name: "collect", Help: "collect data and upload them"},
+	{Name: "collect advisor", Help: "run Advisor", Args: "[-- MODULE FLAGS...]", Flags: archiveFlags, Parse: parseCollect(modules.GetAdvisorModule)},
+	{Name: "collect compliance", Help: "run compliance", Args: "[-- MODULE FLAGS...]", Flags: archiveFlags, Parse: parseCollect(modules.GetComplianceModule)},
+	{Name: "upload", Help: "upload archive from this path", Args: "PATH", Flags: []Flag{
+		{"", 's', "content-type", "upload archive with this content type", []string{}},
+	}, Parse: parseUpload},
+	{Name: "results", Help: "display Advisor report", Flags: []Flag{
+		{"", 's', "sort", "sort Advisor report by 'severity', 'category', 'rule' or 'date'", []string{}},
+		{"", 's', "severity", "only display Advisor report of this severity", []string{}},
+		{"", 's', "category", "only display Advisor report of this category", []string{}},
+	}, Parse: parseResults},
+}
+
+// legacyFlagCommands maps flags of the legacy interface onto commands replacing them.
+//
+// When multiple flags are set, the first match is reported.
+var legacyFlagCommands = []struct {
+	Flag    string
+	Command string
+}{
+	{"register", "host register"},
+	{"unregister", "host unregister"},
+	{"status", "host status"},
+	{"checkin", "host checkin"},
+	{"test-connection", "host test-connection"},
+	{"display-name", "inventory set-name"},
+	{"ansible-host", "inventory set-name"},
+	{"facts", "inventory tags"},
+	{"compliance", "collect compliance"},
+	{"payload", "upload"},
+	{"check-results", "results"},
+	{"show-results", "results"},
+}
+
+// buildLegacyHint suggests the command replacing the legacy flags that were used.
+func buildLegacyHint(cmd *cli.Command) string {
+	for _, legacy := range legacyFlagCommands {
+		if cmd.IsSet(legacy.Flag) {
+			return fmt.Sprintf("Flag '--%s' is deprecated, use 'insights-client %s' instead.", legacy.Flag, legacy.Command)
+		}
+	}
+	return ""
+}
+
+// buildCommands converts the proxy objects into a tree of cli.Command objects.
+func buildCommands() []*cli.Command {
+	var groups []*cli.Command
+	for _, command := range cliCommands {
+		group, name, nested := strings.Cut(command.Name, " ")
+		if !nested {
+			name = group
+		}
+		cliCommand := &cli.Command{
+			Name:            name,
+			Usage:           command.Help,
+			ArgsUsage:       command.Args,
+			HideHelpCommand: true,
+			Suggest:         true,
+			Flags:           buildFlags(command.Flags),
+		}
+		if command.Parse != nil {
+			cliCommand.Action = runCommand
+		}
+
+		if !nested {
+			groups = append(groups, cliCommand)
+			continue
+		}
+		parent := findCommand(groups, group)
+		if parent == nil {
+			panic(fmt.Sprintf("Command group of '%s' is not defined", command.Name))
+		}
+		parent.Commands = append(parent.Commands, cliCommand)
+	}
+	return groups
+}
+
+// findCommand returns the command of the name, or nil.
+func findCommand(commands []*cli.Command, name string) *cli.Command {
+	for _, command := range commands {
+		if command.Name == name {
+			return command
+		}
+	}
+	return nil
+}
+
+// commandName returns the name of the command without the program name, e.g. `host register`.
+func commandName(cmd *cli.Command) string {
+	return strings.TrimPrefix(cmd.FullName(), cmd.Root().Name+" ")
+}
+
+// lookupCommand returns the proxy object of the command.
+func lookupCommand(cmd *cli.Command) (*Command, bool) {
+	name := commandName(cmd)
+	for i := range cliCommands {
+		if cliCommands[i].Name == name {
+			return &cliCommands[i], true
+		}
+	}
+	return nil, false
+}
+
+// parseCommand converts the command into a clean structure.
+//
+// Flags of the legacy interface cannot be combined with commands.
+func parseCommand(cmd *cli.Command) (*impl.Input, error) {
+	command, ok := lookupCommand(cmd)
+	if !ok || command.Parse == nil {
+		return nil, internal.NewError(internal.ErrInput, fmt.Errorf("unknown command: %s", cmd.FullName()), "Not implemented.")
+	}
+
+	root := cmd.Root()
+	for _, flag := range root.Flags {
+		name := flag.Names()[0]
+		if !slices.Contains(cliGlobalFlags, name) && root.IsSet(name) {
+			return nil, internal.NewError(
+				internal.ErrInput, fmt.Errorf("legacy flag with command: %s", name),
+				fmt.Sprintf("Flag '--%s' cannot be used with command '%s'.", name, command.Name),
+			)
+		}
+	}
+
+	input, err := parseGlobalFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if parseErr := command.Parse(cmd, input); parseErr != nil {
+		return nil, parseErr
+	}
+	return input, nil
+}
+
+func runCommand(_ context.Context, cmd *cli.Command) error {
+	input, err := parseCommand(cmd)
+	if err != nil {
+		return err
+	}
+	return runInput(cmd, input)
+}
+
+// expectArgs ensures the command received the number of positional arguments.
+//
+// Negative maximum allows any number of arguments.
+func expectArgs(cmd *cli.Command, minimum, maximum int) internal.IError {
+	count := cmd.Args().Len()
+	if count >= minimum && (maximum < 0 || count <= maximum) {
+		return nil
+	}
+	if cmd.ArgsUsage == "" {
+		return internal.NewError(internal.ErrInput, nil, fmt.Sprintf("Command '%s' does not accept arguments.", commandName(cmd)))
+	}
+	return internal.NewError(internal.ErrInput, nil, fmt.Sprintf("Command '%s' expects arguments '%s'.", commandName(cmd), cmd.ArgsUsage))
+}
+
+// parseAction creates a parser of commands that only select the action.
+func parseAction(action impl.InputAction) func(*cli.Command, *impl.Input) internal.IError {
+	return func(cmd *cli.Command, input *impl.Input) internal.IError {
+		if err := expectArgs(cmd, 0, 0); err != nil {
+			return err
+		}
+		input.Action = action
+		return nil
+	}
+}
+
+func parseHostRegister(cmd *cli.Command, input *impl.Input) internal.IError {
+	if err := expectArgs(cmd, 0, 0); err != nil {
+		return err
+	}
+	input.Action = impl.ARegister
+	input.Args = impl.ARegisterArgs{
+		Group:           cmd.String("group"),
+		DisplayName:     cmd.String("display-name"),
+		AnsibleHostname: cmd.String("ansible-host"),
+	}
+	return nil
+}
+
+func parseInventorySetName(cmd *cli.Command, input *impl.Input) internal.IError {
+	if err := expectArgs(cmd, 0, 0); err != nil {
+		return err
+	}
+	switch {
+	case cmd.IsSet("display-name") && cmd.IsSet("ansible-host"):
+		input.Action = impl.ASetHostFields
+		input.Args = impl.ASetHostFieldsArgs{Fields: map[string]string{
+			"display_name": cmd.String("display-name"),
+			"ansible_host": cmd.String("ansible-host"),
+		}}
+	case cmd.IsSet("display-name"):
+		input.Action = impl.ASetDisplayName
+		input.Args = impl.ASetDisplayNameArgs{Name: cmd.String("display-name")}
+	case cmd.IsSet("ansible-host"):
+		input.Action = impl.ASetAnsibleHostname
+		input.Args = impl.ASetAnsibleHostnameArgs{Name: cmd.String("ansible-host")}
+	default:
+		return internal.NewError(internal.ErrInput, nil, "Either '--display-name' or '--ansible-host' has to be set.")
+	}
+	return nil
+}
+
+func parseInventoryTags(cmd *cli.Command, input *impl.Input) internal.IError {
+	if err := expectArgs(cmd, 1, -1); err != nil {
+		return err
+	}
+	namespace := cmd.Args().First()
+	if cmd.Args().Len() == 1 {
+		input.Action = impl.AShowFacts
+		input.Args = impl.AShowFactsArgs{Namespace: namespace}
+		return nil
+	}
+
+	facts, err := parseFacts(cmd.Args().Tail())
+	if err != nil {
+		return err
+	}
+	input.Action = impl.ASetFacts
+	input.Args = impl.ASetFactsArgs{Namespace: namespace, Facts: facts}
+	return nil
+}
+
+// parseCollect creates a parser of commands running the collector of the module.
+func parseCollect(module func() *modules.Module) func(*cli.Command, *impl.Input) internal.IError {
+	return func(cmd *cli.Command, input *impl.Input) internal.IError {
+		var set []string
+		for _, flag := range archiveFlags {
+			if cmd.IsSet(flag.Name) {
+				set = append(set, "--"+flag.Name)
+			}
+		}
+		if len(set) > 1 {
+			return internal.NewError(internal.ErrInput, nil, fmt.Sprintf("Flags %s cannot be used together.", strings.Join(set, ", ")))
+		}
+
+		input.Action = impl.ARunModule
+		input.Args = impl.ARunModuleArgs{Command: module().ArchiveCommandName}
+		return parseModuleFlags(cmd, input)
+	}
+}
+
+func parseUpload(cmd *cli.Command, input *impl.Input) internal.IError {
+	if err := expectArgs(cmd, 1, 1); err != nil {
+		return err
+	}
+	if !cmd.IsSet("content-type") {
+		return internal.NewError(internal.ErrInput, nil, "Flag '--content-type' has to be set.")
+	}
+	input.Action = impl.AUploadLocalArchive
+	input.Args = impl.AUploadLocalArchiveArgs{
+		Path:        cmd.Args().First(),
+		ContentType: cmd.String("content-type"),
+	}
+	return nil
+}
+
+func parseResults(cmd *cli.Command, input *impl.Input) internal.IError {
+	if err := expectArgs(cmd, 0, 0); err != nil {
+		return err
+	}
+	input.Action = impl.AShowResults
+	input.Args = impl.AShowResultsArgs{
+		SortBy:   cmd.String("sort"),
+		Severity: cmd.String("severity"),
+		Category: cmd.String("category"),
+	}
+	return nil
+}
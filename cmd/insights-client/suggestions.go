@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/m-horky/insights-client-next/internal"
+)
+
+// maxFlagSuggestions limits the number of combinations suggested for invalid input.
+const maxFlagSuggestions = 5
+
+// flagSuggestion is a valid combination of flags close to the entered one.
+type flagSuggestion struct {
+	Combination []string
+	// Added are flags missing in the entered combination.
+	Added []string
+	// Removed are entered flags that are not part of the combination.
+	Removed []string
+	// Cost is the number of changes needed; replacing a flag by a similar one counts once.
+	Cost int
+}
+
+// isFlagCombination reports whether the sorted flags are a valid combination.
+func isFlagCombination(flags []string) bool {
+	for _, combination := range cliFlagCombinations {
+		sorted := slices.Clone(combination)
+		sort.Strings(sorted)
+		if slices.Equal(sorted, flags) {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestFlagCombinations finds valid combinations closest to the flags.
+//
+// Only combinations sharing at least one flag are considered. They are ranked by the number
+// of flags that have to be added or removed; a removed flag replaced by a flag with a similar
+// name is likely a typo, and it is counted as a single change.
+func suggestFlagCombinations(flags []string) []flagSuggestion {
+	var suggestions []flagSuggestion
+	for _, combination := range cliFlagCombinations {
+		suggestion := flagSuggestion{Combination: combination}
+		for _, flag := range combination {
+			if !slices.Contains(flags, flag) {
+				suggestion.Added = append(suggestion.Added, flag)
+			}
+		}
+		for _, flag := range flags {
+			if !slices.Contains(combination, flag) {
+				suggestion.Removed = append(suggestion.Removed, flag)
+			}
+		}
+		if len(suggestion.Removed) == len(flags) {
+			continue
+		}
+
+		suggestion.Cost = len(suggestion.Added) + len(suggestion.Removed)
+		for _, removed := range suggestion.Removed {
+			for _, added := range suggestion.Added {
+				if isSimilarFlag(removed, added) {
+					suggestion.Cost--
+					break
+				}
+			}
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool { return suggestions[i].Cost < suggestions[j].Cost })
+	for i := range suggestions {
+		if suggestions[i].Cost != suggestions[0].Cost || i == maxFlagSuggestions {
+			return suggestions[:i]
+		}
+	}
+	return suggestions
+}
+
+// isSimilarFlag reports whether the names differ in at most a third of their characters.
+func isSimilarFlag(a, b string) bool {
+	return levenshtein(a, b)*3 <= max(len(a), len(b))
+}
+
+// levenshtein computes the edit distance of the strings.
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			substitution := previous[j-1]
+			if a[i-1] != b[j-1] {
+				substitution++
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, substitution)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// findFlagConflicts returns pairs of flags that do not appear together in any valid combination.
+func findFlagConflicts(flags []string) [][2]string {
+	var conflicts [][2]string
+	for i, a := range flags {
+		for _, b := range flags[i+1:] {
+			compatible := slices.ContainsFunc(cliFlagCombinations, func(combination []string) bool {
+				return slices.Contains(combination, a) && slices.Contains(combination, b)
+			})
+			if !compatible {
+				conflicts = append(conflicts, [2]string{a, b})
+			}
+		}
+	}
+	return conflicts
+}
+
+// compatibleFlags returns flags that can be added to the flags, in the order of cliRootFlags.
+func compatibleFlags(flags []string) []string {
+	var compatible []string
+	for _, flag := range cliRootFlags {
+		if slices.Contains(flags, flag.Name) || slices.Contains(compatible, flag.Name) {
+			continue
+		}
+		if slices.Contains(cliGlobalFlags, flag.Name) {
+			compatible = append(compatible, flag.Name)
+			continue
+		}
+		for _, combination := range cliFlagCombinations {
+			if !slices.Contains(combination, flag.Name) {
+				continue
+			}
+			if !slices.ContainsFunc(flags, func(set string) bool { return !slices.Contains(combination, set) }) {
+				compatible = append(compatible, flag.Name)
+				break
+			}
+		}
+	}
+	return compatible
+}
+
+// buildFlagCombinationHelp explains why the flags cannot be used together and what to use instead.
+func buildFlagCombinationHelp(flags []string) string {
+	help := []string{"This flag combination is not valid."}
+	for _, conflict := range findFlagConflicts(flags) {
+		help = append(help, fmt.Sprintf("Flag '--%s' cannot be used with '--%s'.", conflict[0], conflict[1]))
+	}
+
+	suggestions := suggestFlagCombinations(flags)
+	if len(suggestions) == 0 {
+		return strings.Join(help, "\n")
+	}
+	help = append(help, "Did you mean:")
+	for _, suggestion := range suggestions {
+		help = append(help, fmt.Sprintf("  insights-client %s (%s)", buildFlagCombinationUsage(suggestion.Combination), buildFlagSuggestionChanges(suggestion)))
+	}
+	return strings.Join(help, "\n")
+}
+
+// buildFlagSuggestionChanges describes the changes leading to the suggestion, e.g. `add '--facts', remove '--status'`.
+func buildFlagSuggestionChanges(suggestion flagSuggestion) string {
+	quote := func(flags []string) string {
+		quoted := make([]string, len(flags))
+		for i, flag := range flags {
+			quoted[i] = fmt.Sprintf("'--%s'", flag)
+		}
+		return strings.Join(quoted, ", ")
+	}
+	var changes []string
+	if len(suggestion.Added) > 0 {
+		changes = append(changes, "add "+quote(suggestion.Added))
+	}
+	if len(suggestion.Removed) > 0 {
+		changes = append(changes, "remove "+quote(suggestion.Removed))
+	}
+	return strings.Join(changes, ", ")
+}
+
+// buildFlagCombinationUsage constructs a string out of the flags, e.g. `--facts VALUE --set-fact VALUE`.
+func buildFlagCombinationUsage(combination []string) string {
+	var usage []string
+	for _, name := range combination {
+		usage = append(usage, "--"+name)
+		for _, flag := range cliRootFlags {
+			if flag.Name == name && flag.Type != 'b' {
+				usage = append(usage, "VALUE")
+			}
+		}
+	}
+	return strings.Join(usage, " ")
+}
+
+// completeCLI prints commands and flags that can be combined with the flags already entered.
+func completeCLI(_ context.Context, cmd *cli.Command) {
+	setFlags := getSetFlags(cmd)
+	if len(setFlags) == 0 {
+		for _, command := range cmd.Commands {
+			if !command.Hidden {
+				_, _ = fmt.Fprintln(cmd.Root().Writer, command.Name)
+			}
+		}
+	}
+	for _, flag := range compatibleFlags(setFlags) {
+		_, _ = fmt.Fprintln(cmd.Root().Writer, "--"+flag)
+	}
+}
+
+// runFlagCombinations prints valid combinations of flags, to be used for documentation.
+func runFlagCombinations(_ context.Context, cmd *cli.Command) error {
+	input, err := parseGlobalFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if input.Format == internal.JSON {
+		data, jsonErr := json.MarshalIndent(cliFlagCombinations, "", "  ")
+		if jsonErr != nil {
+			return internal.NewError(nil, jsonErr, "Could not format output.")
+		}
+		_, _ = fmt.Fprintln(cmd.Root().Writer, string(data))
+		return nil
+	}
+	for _, combination := range cliFlagCombinations {
+		_, _ = fmt.Fprintln(cmd.Root().Writer, buildFlagCombinationUsage(combination))
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// CachedFile is a downloaded file stored along with its HTTP cache validators.
+//
+// The validators are stored next to the file, with `.meta` suffix.
+type CachedFile struct {
+	Path         string `json:"-"`
+	Data         []byte `json:"-"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// ReadCachedFile loads a cached file and its validators.
+//
+// Missing validators are not considered an error, the file is returned without them.
+func ReadCachedFile(path string) (*CachedFile, IError) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, NewError(ErrNoCache, err, "Could not read cached file.")
+	}
+	cached := &CachedFile{Path: path, Data: data}
+
+	meta, err := os.ReadFile(path + ".meta")
+	if err != nil {
+		slog.Debug("cached file has no metadata", slog.String("path", path))
+		return cached, nil
+	}
+	if err = json.Unmarshal(meta, cached); err != nil {
+		slog.Warn("ignoring malformed cache metadata", slog.String("path", path), slog.String("error", err.Error()))
+	}
+	return cached, nil
+}
+
+// Write stores the file and its validators.
+func (c *CachedFile) Write() IError {
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0o755); err != nil {
+		return NewError(nil, err, "Could not create cache directory.")
+	}
+	if err := os.WriteFile(c.Path, c.Data, 0o600); err != nil {
+		return NewError(nil, err, "Could not write cached file.")
+	}
+	return c.WriteMetadata()
+}
+
+// WriteMetadata stores the validators of a file that is already in place.
+func (c *CachedFile) WriteMetadata() IError {
+	meta, err := json.Marshal(c)
+	if err != nil {
+		return NewError(nil, err, "Could not write cached file.")
+	}
+	if err = os.WriteFile(c.Path+".meta", meta, 0o600); err != nil {
+		return NewError(nil, err, "Could not write cached file.")
+	}
+	slog.Debug("cached file updated", slog.String("path", c.Path), slog.String("etag", c.ETag))
+	return nil
+}
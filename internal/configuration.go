@@ -1,81 +1,141 @@
 package internal
 
 import (
-	"fmt"
 	"log/slog"
-	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
-
-	"github.com/gookit/ini/v2"
 )
 
+// configurationLock guards the cached configuration.
+var configurationLock sync.Mutex
 var configurationInitialized bool = false
 var cachedConfiguration Configuration = getDefaultConfiguration()
 
 // ClearConfiguration clears the configuration cached in memory.
 func ClearConfiguration() {
+	configurationLock.Lock()
+	defer configurationLock.Unlock()
 	configurationInitialized = false
 }
 
 type Configuration struct {
-	APIProtocol         string        `config:"api_protocol"`
+	APIProtocol         string        `config:"api_protocol,enum=http|https"`
 	APIHost             string        `config:"api_host"`
-	APIPort             uint          `config:"api_port"`
-	HTTPTimeout         time.Duration `config:"http_timeout"`
+	APIPort             uint          `config:"api_port,nonzero"`
+	HTTPTimeout         time.Duration `config:"http_timeout,nonzero"`
 	LogLevel            slog.Level    `config:"loglevel"`
-	IdentityCertificate string        `config:"identity_certificate"`
-	IdentityKey         string        `config:"identity_key"`
-	CACertificate       string        `config:"ca_certificate"`
+	IdentityCertificate string        `config:"identity_certificate,path"`
+	IdentityKey         string        `config:"identity_key,path"`
+	// CACertificate is a file or a directory of certificates trusted in addition to the system ones.
+	CACertificate string `config:"ca_certificate,path"`
+	// CertificateWarningDays is how long before its expiry the identity certificate is reported.
+	CertificateWarningDays uint `config:"certificate_warning_days"`
+	// AuthMethod selects how the host authenticates to the API: `cert` (mTLS with the identity certificate),
+	// `basic` (Username and a password) or `token` (bearer token of a service account).
+	// Passwords and client secrets are read from SecretsPath.
+	AuthMethod string `config:"authmethod,enum=cert|basic|token"`
+	Username   string `config:"username"`
+	// TokenURL is the OAuth2 endpoint tokens are requested from with the client credentials flow.
+	TokenURL      string   `config:"token_url,url"`
+	TokenClientID string   `config:"token_client_id"`
+	TokenScopes   []string `config:"token_scopes"`
+
+	// Satellite is set for hosts registered to Red Hat Satellite or Capsule, which proxy the API.
+	// It is detected from rhsm.conf.
+	Satellite bool `config:"satellite"`
+	// Proxy is used to connect to the API. HTTP_PROXY is used when it is not set.
+	Proxy string `config:"proxy,url"`
+
+	// AutoUpdate downloads the newest Core egg before collecting data.
+	AutoUpdate bool `config:"auto_update"`
+	// GPGKeyring contains keys the Core egg has to be signed with.
+	GPGKeyring string `config:"gpg_keyring,path"`
+
+	// EnabledModules are collected by `--collector all-enabled`.
+	EnabledModules []string `config:"enabled_modules"`
+	// ModuleWorkers limits the number of modules collecting at the same time.
+	ModuleWorkers uint `config:"module_workers,nonzero"`
+
+	// HookTimeout limits the run time of each collection hook.
+	HookTimeout time.Duration `config:"hook_timeout,nonzero"`
+
+	// ModuleTimeout limits the run time of module commands. Zero means no limit.
+	ModuleTimeout time.Duration `config:"module_timeout"`
+	// ModuleTimeouts override ModuleTimeout for modules (`module_timeout.advisor`)
+	// and their commands (`module_timeout.advisor.collect`).
+	ModuleTimeouts map[string]time.Duration `config:"module_timeout.*"`
+	// ModuleKillTimeout is a delay between asking the module to terminate and killing it.
+	ModuleKillTimeout time.Duration `config:"module_kill_timeout"`
+	// ModuleScope runs modules in a transient systemd scope with resource limits.
+	ModuleScope      bool   `config:"module_scope"`
+	ModuleSlice      string `config:"module_slice"`
+	ModuleCPUQuota   string `config:"module_cpu_quota"`
+	ModuleMemoryHigh string `config:"module_memory_high"`
+	ModuleMemoryMax  string `config:"module_memory_max"`
+	ModuleTasksMax   string `config:"module_tasks_max"`
+	ModuleIOWeight   string `config:"module_io_weight"`
+
+	// ModuleEnvironment sets variables of all modules (`module_env.NAME`)
+	// or of a single module (`module_env.advisor.NAME`).
+	ModuleEnvironment map[string]string `config:"module_env.*"`
+	// ModuleDropPrivileges runs modules that declare they do not need root with reduced privileges.
+	ModuleDropPrivileges bool `config:"module_drop_privileges"`
+	// ModuleUser is used by unprivileged modules that do not declare their own user.
+	ModuleUser string `config:"module_user"`
 }
 
-// update in-place updates the values of the configuration.
-func (c *Configuration) update(data map[string]string) {
-	for key, value := range data {
-		switch key {
-		case "api_protocol":
-			c.APIProtocol = value
-		case "api_host":
-			c.APIHost = value
-		case "api_port":
-			if number, err := strconv.ParseUint(value, 10, 32); err == nil {
-				c.APIPort = uint(number)
-			} else {
-				slog.Warn("ignoring malformed API port", slog.String("value", value))
-			}
-		case "loglevel":
-			switch strings.ToLower(value) {
-			case "debug":
-				c.LogLevel = slog.LevelDebug
-			case "info":
-				c.LogLevel = slog.LevelInfo
-			case "warn":
-				c.LogLevel = slog.LevelWarn
-			default:
-				c.LogLevel = slog.LevelError
-			}
-		case "identity_certificate":
-			c.IdentityCertificate = value
-		case "identity_key":
-			c.IdentityKey = value
-		case "ca_certificate":
-			c.CACertificate = value
+// GetModuleTimeout returns the timeout of the module command.
+//
+// The most specific configuration option wins.
+func (c *Configuration) GetModuleTimeout(command []string) time.Duration {
+	for i := len(command); i > 0; i-- {
+		if timeout, ok := c.ModuleTimeouts[strings.Join(command[:i], ".")]; ok {
+			return timeout
 		}
 	}
+	return c.ModuleTimeout
+}
+
+// GetModuleEnvironment returns variables set for the module.
+//
+// Module-specific variables override the ones set for all modules.
+func (c *Configuration) GetModuleEnvironment(module string) map[string]string {
+	result := make(map[string]string)
+	for key, value := range c.ModuleEnvironment {
+		if !strings.Contains(key, ".") {
+			result[key] = value
+		}
+	}
+	for key, value := range c.ModuleEnvironment {
+		if name, found := strings.CutPrefix(key, module+"."); found {
+			result[name] = value
+		}
+	}
+	return result
 }
 
 // GetConfiguration loads configuration from a filesystem.
 //
 // It caches its value internally, so it can be called multiple times with no overhead.
-// Call ClearConfiguration to force reload.
+// Call ClearConfiguration to force reload. It is safe for concurrent use.
+// Problems of the configuration are logged, see CheckConfiguration.
 func GetConfiguration() Configuration {
+	configurationLock.Lock()
+	defer configurationLock.Unlock()
 	if configurationInitialized {
 		return cachedConfiguration
 	}
 
-	config := getConfigurationFromPath(ConfigPath)
+	config, issues := NewConfigurationLoader().Load()
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			slog.Warn("ignoring configuration value", slog.String("issue", issue.String()))
+		} else {
+			slog.Debug("configuration issue", slog.String("issue", issue.String()))
+		}
+	}
 	cachedConfiguration = config
 	configurationInitialized = true
 	return cachedConfiguration
@@ -84,52 +144,37 @@ func GetConfiguration() Configuration {
 // getDefaultConfiguration loads sane defaults.
 func getDefaultConfiguration() Configuration {
 	return Configuration{
-		APIProtocol:         "https",
-		APIHost:             "cert.console.redhat.com",
-		APIPort:             443,
-		HTTPTimeout:         10 * time.Second,
-		LogLevel:            slog.LevelDebug,
-		IdentityCertificate: "/etc/pki/consumer/cert.pem",
-		IdentityKey:         "/etc/pki/consumer/key.pem",
-		CACertificate:       "/etc/rhsm/ca/redhat-ep.pem",
-	}
-}
-
-// getConfigurationFromPath loads configuration from path and its .d/ subdirectory.
-func getConfigurationFromPath(path string) Configuration {
-	config := getDefaultConfiguration()
-	for _, file := range expandConfigurationPath(path) {
-		err := ini.LoadFiles(file)
-		if err != nil {
-			slog.Warn("ignoring malformed file", slog.String("file", file), slog.String("error", err.Error()))
-			continue
-		}
-		config.update(ini.Data()["insights-client"])
+		APIProtocol:            "https",
+		APIHost:                "cert.console.redhat.com",
+		APIPort:                443,
+		HTTPTimeout:            10 * time.Second,
+		LogLevel:               slog.LevelDebug,
+		IdentityCertificate:    filepath.Join(rhsmDefaultConsumerCertDir, "cert.pem"),
+		IdentityKey:            filepath.Join(rhsmDefaultConsumerCertDir, "key.pem"),
+		CACertificate:          filepath.Join(rhsmDefaultCACertDir, "redhat-ep.pem"),
+		AuthMethod:             "cert",
+		CertificateWarningDays: 30,
+		AutoUpdate:             true,
+		GPGKeyring:             "/etc/insights-client/redhattools.pub.gpg",
+		EnabledModules:         []string{"advisor"},
+		ModuleWorkers:          2,
+		HookTimeout:            5 * time.Minute,
+		ModuleTimeout:          time.Hour,
+		ModuleKillTimeout:      10 * time.Second,
+		ModuleScope:            false,
+		// These mirror limits of insights-client-upload.service
+		ModuleCPUQuota:   "30%",
+		ModuleMemoryHigh: "1G",
+		ModuleMemoryMax:  "2G",
+		ModuleTasksMax:   "300",
+		// Modules declaring they do not need root should not get it
+		ModuleDropPrivileges: true,
+		ModuleUser:           "nobody",
 	}
-	return config
 }
 
-// expandConfigurationPath expands `.../path.conf` to also include all files under `.../path.conf.d/`.
-func expandConfigurationPath(path string) []string {
-	dir := fmt.Sprintf("%s.d", path)
-	stat, err := os.Stat(dir)
-	if err != nil {
-		return []string{path}
-	}
-	if !stat.IsDir() {
-		return []string{path}
-	}
-
-	paths, err := os.ReadDir(dir)
-	if err != nil {
-		slog.Warn("could not list configuration files", slog.String("error", err.Error()))
-		return []string{path}
-	}
-
-	var result = make([]string, len(paths)+1)
-	result[0] = path
-	for i, file := range paths {
-		result[i+1] = filepath.Join(dir, file.Name())
-	}
-	return result
+// CheckConfiguration reports problems of the configuration files and environment variables.
+func CheckConfiguration() []ConfigurationIssue {
+	_, issues := NewConfigurationLoader().Load()
+	return issues
 }
@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ConfigurationLoader reads the configuration file, its drop-in files and the environment.
+//
+// Every file is parsed on its own and the loader holds no other state,
+// so multiple loaders can be used independently and concurrently.
+type ConfigurationLoader struct {
+	// Root is prepended to Path, e.g. a temporary directory in tests. Empty means `/`.
+	Root string
+	// Path is the main configuration file. Drop-in files are read from `Path.d/`.
+	Path string
+	// RHSMPath is the subscription-manager configuration, used as the lowest priority layer.
+	// Empty means it is not read.
+	RHSMPath string
+}
+
+// NewConfigurationLoader creates a loader of the system configuration at ConfigPath and RHSMConfigPath.
+func NewConfigurationLoader() *ConfigurationLoader {
+	return &ConfigurationLoader{Path: ConfigPath, RHSMPath: RHSMConfigPath}
+}
+
+// Load applies the configuration files and the environment onto the defaults.
+//
+// Problems found on the way are returned, the affected values are ignored.
+func (l *ConfigurationLoader) Load() (Configuration, []ConfigurationIssue) {
+	config, _, issues := l.load()
+	return config, issues
+}
+
+// load additionally returns the origin of each key that was set.
+func (l *ConfigurationLoader) load() (Configuration, map[string]string, []ConfigurationIssue) {
+	config := getDefaultConfiguration()
+	origins := make(map[string]string)
+	layers, issues := l.layers()
+	for _, layer := range layers {
+		applied, layerIssues := config.update(layer)
+		issues = append(issues, layerIssues...)
+		for _, key := range applied {
+			origins[key] = layer.Origin
+		}
+	}
+
+	// Server options of rhsm.conf describe the Satellite the host is registered to;
+	// they do not apply when a higher layer points the client to another API host.
+	if l.RHSMPath != "" {
+		rhsm := filepath.Join(l.Root, l.RHSMPath)
+		defaults := getDefaultConfiguration()
+		if origins["api_host"] != rhsm && origins["satellite"] == rhsm {
+			config.Satellite = defaults.Satellite
+			delete(origins, "satellite")
+		}
+		if origins["api_host"] != rhsm && origins["api_port"] == rhsm {
+			config.APIPort = defaults.APIPort
+			delete(origins, "api_port")
+		}
+	}
+	return config, origins, issues
+}
+
+// dropInDirectory returns the directory of the drop-in files.
+func (l *ConfigurationLoader) dropInDirectory() string {
+	return filepath.Join(l.Root, l.Path+".d")
+}
+
+// files returns the main configuration file followed by drop-in files in lexical order.
+//
+// Only `*.conf` files are drop-ins; editor backups, package manager leftovers
+// (`.rpmsave`, `.rpmnew`) and hidden files are skipped.
+func (l *ConfigurationLoader) files() []string {
+	result := []string{filepath.Join(l.Root, l.Path)}
+
+	entries, err := os.ReadDir(l.dropInDirectory())
+	if errors.Is(err, os.ErrNotExist) {
+		return result
+	}
+	if err != nil {
+		slog.Warn("could not list configuration files", slog.String("error", err.Error()))
+		return result
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".conf" {
+			slog.Debug("skipping configuration drop-in", slog.String("file", name))
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result = append(result, filepath.Join(l.dropInDirectory(), name))
+	}
+	return result
+}
+
+// configurationLayer is a set of raw values coming from a single origin.
+type configurationLayer struct {
+	Origin string
+	Data   map[string]string
+	// Lines map keys to lines they were set on, if the origin is a file.
+	Lines map[string]int
+}
+
+// layers reads the configuration files and the environment.
+//
+// Layers are ordered from the lowest to the highest priority.
+// Files that do not exist are skipped, lines that cannot be parsed are reported.
+func (l *ConfigurationLoader) layers() ([]configurationLayer, []ConfigurationIssue) {
+	var layers []configurationLayer
+	var issues []ConfigurationIssue
+	if l.RHSMPath != "" {
+		path := filepath.Join(l.Root, l.RHSMPath)
+		rhsm, err := ReadRHSMConfiguration(path)
+		if err == nil {
+			layers = append(layers, rhsm.layer(path))
+		} else {
+			slog.Debug("not using rhsm configuration", slog.String("error", err.Error()))
+		}
+	}
+	for _, file := range l.files() {
+		data, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			issues = append(issues, ConfigurationIssue{Origin: file, Severity: SeverityError, Message: err.Error()})
+			continue
+		}
+		document, fileIssues := parseINI(file, data)
+		issues = append(issues, fileIssues...)
+
+		layer := configurationLayer{Origin: file, Data: make(map[string]string), Lines: make(map[string]int)}
+		for key, value := range document.Sections[ConfigurationSection] {
+			layer.Data[key] = value.Value
+			layer.Lines[key] = value.Line
+		}
+		layers = append(layers, layer)
+	}
+
+	for _, key := range configurationKeys() {
+		if strings.HasSuffix(key, ".*") {
+			continue
+		}
+		name := ConfigurationEnvironmentPrefix + strings.ToUpper(key)
+		if value, ok := os.LookupEnv(name); ok {
+			layers = append(layers, configurationLayer{Origin: "env:" + name, Data: map[string]string{key: value}})
+		}
+	}
+	return layers, issues
+}
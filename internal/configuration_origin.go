@@ -0,0 +1,229 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ConfigurationSection is the INI section the client reads its configuration from.
+const ConfigurationSection = "insights-client"
+
+// ConfigurationEnvironmentPrefix prefixes environment variables overriding configuration keys,
+// e.g. `INSIGHTS_CLIENT_LOGLEVEL`.
+const ConfigurationEnvironmentPrefix = "INSIGHTS_CLIENT_"
+
+// ConfigurationSetDropIn is a drop-in file written by SetConfigurationValue.
+const ConfigurationSetDropIn = "90-config-set.conf"
+
+// OriginDefault is the origin of values that were not set by the administrator.
+const OriginDefault = "default"
+
+// ConfigurationValue is an effective configuration value along with its origin.
+type ConfigurationValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Origin is OriginDefault, a path to the file, or `env:NAME`.
+	Origin string `json:"origin"`
+}
+
+// configurationKeys returns keys declared by the `config` tags of Configuration.
+//
+// Keys of map fields end with `.*`.
+func configurationKeys() []string {
+	var keys []string
+	for _, field := range configurationSchema() {
+		keys = append(keys, field.Key)
+	}
+	return keys
+}
+
+// IsConfigurationKey reports whether the key is understood by the client.
+func IsConfigurationKey(key string) bool {
+	_, _, ok := lookupConfigurationField(key)
+	return ok
+}
+
+// format converts the configuration to raw values, as they would be written in a file.
+//
+// Passwords in URLs, e.g. of the proxy, are redacted.
+func (c *Configuration) format() map[string]string {
+	values := make(map[string]string)
+	value := reflect.ValueOf(*c)
+	for _, schema := range configurationSchema() {
+		key := schema.Key
+		field := value.Field(schema.Index).Interface()
+		switch field := field.(type) {
+		case map[string]time.Duration:
+			prefix := strings.TrimSuffix(key, "*")
+			for name, duration := range field {
+				values[prefix+name] = duration.String()
+			}
+		case map[string]string:
+			prefix := strings.TrimSuffix(key, "*")
+			for name, text := range field {
+				values[prefix+name] = text
+			}
+		case []string:
+			values[key] = strings.Join(field, ",")
+		case slog.Level:
+			values[key] = strings.ToLower(field.String())
+		case string:
+			values[key] = field
+			if address, err := url.Parse(field); schema.URL && err == nil {
+				values[key] = address.Redacted()
+			}
+		default:
+			values[key] = fmt.Sprintf("%v", field)
+		}
+	}
+	return values
+}
+
+// GetConfigurationValues returns the effective configuration with the origin of each value.
+func GetConfigurationValues() []ConfigurationValue {
+	config, origins, _ := NewConfigurationLoader().load()
+
+	var result []ConfigurationValue
+	for key, value := range config.format() {
+		origin, ok := origins[key]
+		if !ok {
+			origin = OriginDefault
+		}
+		result = append(result, ConfigurationValue{Key: key, Value: value, Origin: origin})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
+
+// GetConfigurationValue returns the effective value of a single key.
+func GetConfigurationValue(key string) (*ConfigurationValue, IError) {
+	if !IsConfigurationKey(key) {
+		return nil, NewError(ErrInput, nil, fmt.Sprintf("Unknown configuration key '%s'.", key))
+	}
+	for _, value := range GetConfigurationValues() {
+		if value.Key == key {
+			return &value, nil
+		}
+	}
+	// Keys of map fields do not have to be set at all.
+	return &ConfigurationValue{Key: key, Origin: OriginDefault}, nil
+}
+
+// dropInLock serializes changes of the ConfigurationSetDropIn drop-in file.
+var dropInLock sync.Mutex
+
+// SetConfigurationValue stores the value in the ConfigurationSetDropIn drop-in file.
+//
+// The value is validated before it is written. The file is replaced atomically,
+// so a partially written file is never read by the client. It is only readable by its owner,
+// because values such as the proxy URL may contain credentials.
+//
+// The effective value is returned, with passwords redacted. When another file or an environment
+// variable overrides the drop-in, the value is stored, but an ErrConfiguration error is returned.
+func SetConfigurationValue(key, value string) (*ConfigurationValue, IError) {
+	if strings.ContainsFunc(key, isUnsafeINIKeyRune) {
+		return nil, NewError(ErrInput, nil, fmt.Sprintf("Configuration key %q is not valid.", key))
+	}
+	if !IsConfigurationKey(key) {
+		return nil, NewError(ErrInput, nil, fmt.Sprintf("Unknown configuration key '%s'.", key))
+	}
+	// Line breaks would let the value add keys or sections to the file.
+	if strings.ContainsFunc(value, unicode.IsControl) {
+		return nil, NewError(ErrInput, nil, fmt.Sprintf("Value of key '%s' must not contain control characters.", key))
+	}
+	config := getDefaultConfiguration()
+	_, issues := config.update(configurationLayer{Data: map[string]string{key: value}})
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return nil, NewError(ErrInput, errors.New(issue.Message), fmt.Sprintf("Value of key '%s' is not valid.", key))
+		}
+	}
+
+	path := filepath.Join(NewConfigurationLoader().dropInDirectory(), ConfigurationSetDropIn)
+
+	// Concurrent calls would lose each other's values.
+	dropInLock.Lock()
+	defer dropInLock.Unlock()
+
+	data := make(map[string]string)
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, NewError(nil, err, fmt.Sprintf("Could not read '%s'.", path))
+	}
+	document, _ := parseINI(path, existing)
+	for existingKey, existingValue := range document.Sections[ConfigurationSection] {
+		data[existingKey] = existingValue.Value
+	}
+	data[key] = value
+
+	keys := make([]string, 0, len(data))
+	for existingKey := range data {
+		keys = append(keys, existingKey)
+	}
+	sort.Strings(keys)
+	var content strings.Builder
+	content.WriteString("# This file is managed by 'insights-client --config-set'.\n")
+	content.WriteString("[" + ConfigurationSection + "]\n")
+	for _, existingKey := range keys {
+		content.WriteString(fmt.Sprintf("%s=%s\n", existingKey, data[existingKey]))
+	}
+
+	if err := writeFileAtomically(path, []byte(content.String()), 0o600); err != nil {
+		return nil, NewError(nil, err, fmt.Sprintf("Could not write '%s'.", path))
+	}
+	slog.Info("configuration value set", slog.String("key", key), slog.String("path", path))
+	ClearConfiguration()
+
+	effective, valueErr := GetConfigurationValue(key)
+	if valueErr != nil {
+		return nil, valueErr
+	}
+	if effective.Origin != path {
+		return effective, NewError(ErrConfiguration, nil, fmt.Sprintf(
+			"Configuration key '%s' has been set in '%s', but it is overridden by '%s'.", key, path, effective.Origin,
+		))
+	}
+	return effective, nil
+}
+
+// isUnsafeINIKeyRune reports whether the character would change the meaning of the INI line.
+func isUnsafeINIKeyRune(r rune) bool {
+	return unicode.IsControl(r) || unicode.IsSpace(r) || strings.ContainsRune("=:[]#;", r)
+}
+
+// writeFileAtomically writes the data to a temporary file which then replaces the target.
+func writeFileAtomically(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), perm)
+	}
+	if err != nil {
+		return errors.Join(err, os.Remove(file.Name()))
+	}
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,346 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IssueSeverity describes how serious a configuration issue is.
+type IssueSeverity string
+
+const (
+	// SeverityError issues cause the value to be ignored.
+	SeverityError IssueSeverity = "error"
+	// SeverityWarning issues do not prevent the value from being used.
+	SeverityWarning IssueSeverity = "warning"
+)
+
+// ConfigurationIssue is a problem found in the configuration.
+type ConfigurationIssue struct {
+	// Origin is a path to the file, or `env:NAME`.
+	Origin   string        `json:"origin"`
+	Line     int           `json:"line,omitempty"`
+	Key      string        `json:"key,omitempty"`
+	Severity IssueSeverity `json:"severity"`
+	Message  string        `json:"message"`
+}
+
+func (i ConfigurationIssue) String() string {
+	location := i.Origin
+	if i.Line > 0 {
+		location = fmt.Sprintf("%s:%d", i.Origin, i.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, i.Severity, i.Message)
+}
+
+// configurationField is a configuration key declared by the `config` tag of Configuration.
+//
+// The tag contains the key, optionally followed by comma-separated options:
+// `path` (absolute filesystem path), `url` (URL with a scheme and a host),
+// `nonzero` (zero is not accepted) and `enum=a|b` (one of the listed values).
+// Keys of map fields end with `.*`.
+type configurationField struct {
+	Key     string
+	Index   int
+	Path    bool
+	URL     bool
+	NonZero bool
+	Enum    []string
+}
+
+// deprecatedConfigurationKeys maps keys of the legacy insights-client to their replacements.
+//
+// Their values are applied to the replacement, and they are reported as warnings.
+var deprecatedConfigurationKeys = map[string]string{
+	"cmd_timeout": "module_timeout",
+}
+
+// unsupportedConfigurationKeys are keys of the legacy insights-client without a replacement.
+//
+// They are ignored; since the host may rely on them, e.g. to obfuscate the collected data,
+// they are reported as errors.
+var unsupportedConfigurationKeys = []string{
+	"ansible_host",
+	"auto_config",
+	"base_url",
+	"cert_verify",
+	"content_redaction_file",
+	"core_collect",
+	"display_name",
+	"gpg",
+	"legacy_upload",
+	"logging_file",
+	"no_schedule",
+	"obfuscate",
+	"obfuscate_hostname",
+	"redaction_file",
+	"remove_file",
+	"upload_url",
+}
+
+// secretConfigurationKeys have to be set in the SecretsPath file instead of the configuration.
+var secretConfigurationKeys = []string{"password", "token_client_secret"}
+
+// configurationSchema describes all keys of Configuration.
+//
+// Unsupported tag options are ignored, they are caught by tests.
+func configurationSchema() []configurationField {
+	var schema []configurationField
+	typ := reflect.TypeOf(Configuration{})
+	for i := 0; i < typ.NumField(); i++ {
+		tag := typ.Field(i).Tag.Get("config")
+		if tag == "" {
+			continue
+		}
+		field, _ := parseConfigurationTag(tag)
+		field.Index = i
+		schema = append(schema, field)
+	}
+	return schema
+}
+
+// parseConfigurationTag converts the `config` tag into the field.
+//
+// An error is returned for unsupported options; the rest of the tag is still parsed.
+func parseConfigurationTag(tag string) (configurationField, error) {
+	options := strings.Split(tag, ",")
+	field := configurationField{Key: options[0]}
+	var unsupported []string
+	for _, option := range options[1:] {
+		switch {
+		case option == "path":
+			field.Path = true
+		case option == "url":
+			field.URL = true
+		case option == "nonzero":
+			field.NonZero = true
+		case strings.HasPrefix(option, "enum="):
+			field.Enum = strings.Split(strings.TrimPrefix(option, "enum="), "|")
+		default:
+			unsupported = append(unsupported, option)
+		}
+	}
+	if len(unsupported) > 0 {
+		return field, fmt.Errorf("unsupported configuration options of '%s': %s", field.Key, strings.Join(unsupported, ", "))
+	}
+	return field, nil
+}
+
+// lookupConfigurationField finds the field of the key.
+//
+// For keys of map fields, the part of the key matching the `*` is returned as well.
+func lookupConfigurationField(key string) (*configurationField, string, bool) {
+	for _, field := range configurationSchema() {
+		if field.Key == key {
+			return &field, "", true
+		}
+		if prefix, found := strings.CutSuffix(field.Key, "*"); found && strings.HasPrefix(key, prefix) && len(key) > len(prefix) {
+			return &field, strings.TrimPrefix(key, prefix), true
+		}
+	}
+	return nil, "", false
+}
+
+// set parses the value and stores it into the field of the configuration.
+func (f *configurationField) set(configuration reflect.Value, name, value string) error {
+	target := configuration.Field(f.Index)
+	if target.Kind() == reflect.Map {
+		parsed, err := f.parse(target.Type().Elem(), value)
+		if err != nil {
+			return err
+		}
+		if target.IsNil() {
+			target.Set(reflect.MakeMap(target.Type()))
+		}
+		target.SetMapIndex(reflect.ValueOf(name), parsed)
+		return nil
+	}
+
+	parsed, err := f.parse(target.Type(), value)
+	if err != nil {
+		return err
+	}
+	target.Set(parsed)
+	return nil
+}
+
+// parse converts the raw value into the type of the field.
+func (f *configurationField) parse(typ reflect.Type, value string) (reflect.Value, error) {
+	value = strings.TrimSpace(value)
+
+	switch typ {
+	case reflect.TypeOf(time.Duration(0)):
+		duration, err := parseDuration(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if f.NonZero && duration == 0 {
+			return reflect.Value{}, errors.New("duration must not be zero")
+		}
+		return reflect.ValueOf(duration), nil
+	case reflect.TypeOf(slog.Level(0)):
+		level, err := parseLogLevel(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(level), nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		enabled, err := parseBool(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(enabled), nil
+	case reflect.Uint:
+		number, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("'%s' is not a non-negative number", value)
+		}
+		if f.NonZero && number == 0 {
+			return reflect.Value{}, errors.New("number must not be zero")
+		}
+		return reflect.ValueOf(uint(number)), nil
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return reflect.ValueOf(items), nil
+	case reflect.String:
+		if len(f.Enum) > 0 {
+			// The legacy client accepted upper-case values, e.g. `authmethod=BASIC`.
+			for _, allowed := range f.Enum {
+				if strings.EqualFold(value, allowed) {
+					return reflect.ValueOf(allowed), nil
+				}
+			}
+			return reflect.Value{}, fmt.Errorf("'%s' is not one of %s", value, strings.Join(f.Enum, ", "))
+		}
+		if err := f.validateString(value); err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(value), nil
+	}
+	return reflect.Value{}, fmt.Errorf("type %s is not supported", typ)
+}
+
+// validateString checks the string value against the `path` and `url` options of the field.
+//
+// Empty paths and URLs are accepted, they unset the value.
+func (f *configurationField) validateString(value string) error {
+	if f.Path && value != "" && !filepath.IsAbs(value) {
+		return fmt.Errorf("'%s' is not an absolute path", value)
+	}
+	if f.URL && value != "" {
+		address, err := url.Parse(value)
+		if err != nil || address.Scheme == "" || address.Host == "" {
+			return fmt.Errorf("'%s' is not a URL", value)
+		}
+	}
+	return nil
+}
+
+// parseDuration parses Go durations (`1m30s`) and plain seconds (`90`) used by the legacy client.
+func parseDuration(value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, floatErr := strconv.ParseFloat(value, 64)
+		if floatErr != nil {
+			return 0, fmt.Errorf("'%s' is not a duration", value)
+		}
+		duration = time.Duration(seconds * float64(time.Second))
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("'%s' is negative", value)
+	}
+	return duration, nil
+}
+
+// parseBool accepts the boolean values understood by Python's configparser.
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "1", "yes", "true", "on":
+		return true, nil
+	case "0", "no", "false", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("'%s' is not a boolean", value)
+}
+
+// parseLogLevel accepts slog levels and the levels of Python's logging.
+func parseLogLevel(value string) (slog.Level, error) {
+	switch strings.ToLower(value) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error", "critical":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("'%s' is not one of debug, info, warning, error", value)
+}
+
+// update in-place updates the values of the configuration.
+//
+// Values are parsed according to the configuration schema. Malformed values and keys
+// that are not supported anymore are skipped, unknown keys are ignored and deprecated keys
+// are mapped to their replacements; all of them are reported. Keys that were applied are returned.
+func (c *Configuration) update(layer configurationLayer) ([]string, []ConfigurationIssue) {
+	keys := make([]string, 0, len(layer.Data))
+	for key := range layer.Data {
+		keys = append(keys, key)
+	}
+	// Keys are sorted to keep the reports stable.
+	sort.Strings(keys)
+
+	var applied []string
+	var issues []ConfigurationIssue
+	configuration := reflect.ValueOf(c).Elem()
+	for _, key := range keys {
+		report := func(severity IssueSeverity, message string) {
+			issues = append(issues, ConfigurationIssue{layer.Origin, layer.Lines[key], key, severity, message})
+		}
+
+		if slices.Contains(secretConfigurationKeys, key) {
+			report(SeverityWarning, fmt.Sprintf("'%s' is ignored, it has to be set in '%s'", key, SecretsPath))
+			continue
+		}
+		if slices.Contains(unsupportedConfigurationKeys, key) {
+			report(SeverityError, fmt.Sprintf("'%s' is not supported anymore and is ignored", key))
+			continue
+		}
+
+		name := key
+		if replacement, deprecated := deprecatedConfigurationKeys[key]; deprecated {
+			report(SeverityWarning, fmt.Sprintf("'%s' is deprecated, use '%s' instead", key, replacement))
+			name = replacement
+		}
+
+		field, suffix, ok := lookupConfigurationField(name)
+		if !ok {
+			report(SeverityWarning, fmt.Sprintf("unknown key '%s' is ignored", key))
+			continue
+		}
+		if err := field.set(configuration, suffix, layer.Data[key]); err != nil {
+			report(SeverityError, fmt.Sprintf("malformed value of '%s': %s", key, err.Error()))
+			continue
+		}
+		applied = append(applied, name)
+	}
+	return applied, issues
+}
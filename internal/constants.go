@@ -5,6 +5,14 @@ var Version = "development"
 // ConfigPath points to a file where a configuration file is stored.
 var ConfigPath = "/etc/insights-client/insights-client.conf"
 
+// SecretsPath points to a file with credentials of the basic and token authentication.
+// It has to be owned by the user running the client and must not be accessible by anyone else.
+var SecretsPath = "/etc/insights-client/secrets.conf"
+
+// RHSMConfigPath points to the subscription-manager configuration file.
+// Its values are used when they are not set in the client configuration.
+var RHSMConfigPath = "/etc/rhsm/rhsm.conf"
+
 // LogPath points to a file into which logs should be written.
 var LogPath = "/var/log/insights-client/insights-client.log"
 
@@ -14,8 +22,26 @@ var ArchiveDirectoryParentPath = "/var/cache/insights-client/"
 // DefaultModuleName is run when CLI did not specify anything else.
 var DefaultModuleName = "advisor"
 
+// HooksDirectoryPath contains `<stage>.d/` directories with executables run during collection.
+var HooksDirectoryPath = "/etc/insights-client/hooks/"
+
+// DenylistPath points to a file describing data that must not be collected.
+var DenylistPath = "/etc/insights-client/remove.conf"
+
+// FileRedactionPath points to a file describing data that must not be collected, in YAML format.
+var FileRedactionPath = "/etc/insights-client/file-redaction.yaml"
+
 // MachineIDFilePath points to a file where the client UUID is stored.
 var MachineIDFilePath = "/etc/insights-client/machine-id"
 var DotRegisteredPath = "/etc/insights-client/.registered"
 var DotUnregisteredPath = "/etc/insights-client/.unregistered"
 var TagsPath = "/etc/insights-client/tags.yaml"
+
+// CacheDirectoryPath is a directory where downloaded API data are stored.
+var CacheDirectoryPath = "/var/lib/insights-client/"
+
+// AdvisorReportCachePath points to a file where the last Advisor report is stored.
+var AdvisorReportCachePath = "/var/lib/insights-client/advisor-report.json"
+
+// CollectionRulesCachePath points to a file where the verified collection rules are stored.
+var CollectionRulesCachePath = "/var/lib/insights-client/uploader.v2.json"
@@ -0,0 +1,442 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Denylist describes data that must not leave the host.
+type Denylist struct {
+	// Files are paths or glob patterns of files that are not collected.
+	Files []string
+	// Commands are commands whose output is not collected.
+	Commands []string
+	// Patterns remove lines containing them from the collected data.
+	Patterns []string
+	// PatternRegexes remove lines matching them from the collected data.
+	PatternRegexes []*regexp.Regexp
+	// Keywords are replaced in the collected data, see KeywordReplacement.
+	Keywords []string
+	// Components are Core specs that are not collected. They are only enforced by the Core.
+	// Symbolic spec names listed as files or commands are stored here as well.
+	Components []string
+}
+
+// KeywordReplacement is the format of the token replacing the n-th keyword, as done by the Core.
+const KeywordReplacement = "keyword%d"
+
+// DenylistIssue is a problem found in a denylist file.
+type DenylistIssue struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (i DenylistIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s", i.Path, i.Line, i.Message)
+}
+
+// denylistKeys map keys accepted in both the remove.conf and file-redaction.yaml formats
+// to their canonical names. The Core uses singular names in file-redaction.yaml.
+var denylistKeys = map[string]string{
+	"files":      "files",
+	"file":       "files",
+	"commands":   "commands",
+	"command":    "commands",
+	"patterns":   "patterns",
+	"keywords":   "keywords",
+	"components": "components",
+}
+
+// componentRegex matches spec names, either symbolic (`hostname`) or dotted (`insights.specs.Specs.hostname`).
+var componentRegex = regexp.MustCompile(`^[A-Za-z_][\w.]*$`)
+
+// ReadDenylist loads the denylist from DenylistPath and FileRedactionPath.
+//
+// Missing files are not considered an error. Values with issues are skipped and the issues
+// are returned, so the collection proceeds with the rest of the denylist.
+func ReadDenylist() (*Denylist, []DenylistIssue, IError) {
+	denylist := &Denylist{}
+	var issues []DenylistIssue
+	for _, path := range []string{DenylistPath, FileRedactionPath} {
+		parsed, fileIssues, err := ParseDenylist(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, issue := range fileIssues {
+			slog.Warn("ignoring denylist value", slog.String("issue", issue.String()))
+		}
+		issues = append(issues, fileIssues...)
+		denylist.merge(parsed)
+	}
+	return denylist, issues, nil
+}
+
+// ParseDenylist parses the denylist file and reports problems found in it.
+//
+// Files with `.yaml` or `.yml` suffix are read as file-redaction.yaml, other files as remove.conf.
+// An empty denylist is returned when the file does not exist.
+func ParseDenylist(path string) (*Denylist, []DenylistIssue, IError) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Denylist{}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, NewError(ErrDenylist, err, fmt.Sprintf("Could not read denylist '%s'.", path))
+	}
+
+	var denylist *Denylist
+	var issues []DenylistIssue
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		denylist, issues = parseRedactionYAML(path, data)
+	default:
+		denylist, issues = parseRemoveConf(path, data)
+	}
+	return denylist, issues, nil
+}
+
+// parseRemoveConf parses the `[remove]` section of the INI file with comma-separated values.
+func parseRemoveConf(path string, data []byte) (*Denylist, []DenylistIssue) {
+	denylist := &Denylist{}
+	var issues []DenylistIssue
+
+	section := ""
+	number := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		number++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if section != "remove" {
+				issues = append(issues, DenylistIssue{path, number, fmt.Sprintf("unknown section '%s'", section)})
+			}
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			issues = append(issues, DenylistIssue{path, number, "expected 'key=value'"})
+			continue
+		}
+		if section != "remove" {
+			issues = append(issues, DenylistIssue{path, number, "option is not in section '[remove]'"})
+			continue
+		}
+		var values []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		issues = append(issues, denylist.set(path, number, strings.TrimSpace(key), values)...)
+	}
+	return denylist, issues
+}
+
+// parseRedactionYAML parses the YAML mapping of keys to lists of values.
+func parseRedactionYAML(path string, data []byte) (*Denylist, []DenylistIssue) {
+	denylist := &Denylist{}
+	var issues []DenylistIssue
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return denylist, []DenylistIssue{{path, yamlErrorLine(err), err.Error()}}
+	}
+	if len(document.Content) == 0 {
+		return denylist, nil
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return denylist, []DenylistIssue{{path, root.Line, "expected a mapping of keys to lists"}}
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if _, ok := denylistKeys[key.Value]; !ok {
+			issues = append(issues, unknownDenylistKey(path, key.Line, key.Value))
+			continue
+		}
+		// The Core accepts `patterns: {regex: [...]}` for regular expressions.
+		if key.Value == "patterns" && value.Kind == yaml.MappingNode {
+			issues = append(issues, denylist.parsePatternMapping(path, value)...)
+			continue
+		}
+		if value.Kind != yaml.SequenceNode {
+			issues = append(issues, DenylistIssue{path, value.Line, fmt.Sprintf("'%s' must be a list", key.Value)})
+			continue
+		}
+		for _, item := range value.Content {
+			if item.Kind != yaml.ScalarNode {
+				issues = append(issues, DenylistIssue{path, item.Line, fmt.Sprintf("items of '%s' must be strings", key.Value)})
+				continue
+			}
+			issues = append(issues, denylist.set(path, item.Line, key.Value, []string{item.Value})...)
+		}
+	}
+	return denylist, issues
+}
+
+// parsePatternMapping parses the `regex` list of the patterns mapping.
+func (d *Denylist) parsePatternMapping(path string, value *yaml.Node) []DenylistIssue {
+	var issues []DenylistIssue
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key, items := value.Content[i], value.Content[i+1]
+		if key.Value != "regex" {
+			issues = append(issues, DenylistIssue{path, key.Line, fmt.Sprintf("unknown key 'patterns.%s', expected 'patterns.regex'", key.Value)})
+			continue
+		}
+		if items.Kind != yaml.SequenceNode {
+			issues = append(issues, DenylistIssue{path, items.Line, "'patterns.regex' must be a list"})
+			continue
+		}
+		for _, item := range items.Content {
+			regex, err := regexp.Compile(item.Value)
+			if item.Kind != yaml.ScalarNode || err != nil {
+				issues = append(issues, DenylistIssue{path, item.Line, fmt.Sprintf("pattern '%s' is not a valid regular expression", item.Value)})
+				continue
+			}
+			d.PatternRegexes = append(d.PatternRegexes, regex)
+		}
+	}
+	return issues
+}
+
+// unknownDenylistKey reports the key that is not one of denylistKeys.
+func unknownDenylistKey(path string, line int, key string) DenylistIssue {
+	keys := make([]string, 0, len(denylistKeys))
+	for known := range denylistKeys {
+		keys = append(keys, known)
+	}
+	sort.Strings(keys)
+	return DenylistIssue{path, line, fmt.Sprintf("unknown key '%s', expected one of %s", key, strings.Join(keys, ", "))}
+}
+
+// yamlErrorLine extracts the line number from the YAML error message.
+func yamlErrorLine(err error) int {
+	var line int
+	if _, scanErr := fmt.Sscanf(err.Error(), "yaml: line %d:", &line); scanErr == nil {
+		return line
+	}
+	return 0
+}
+
+// set validates and appends the values of the key.
+//
+// Files and commands that are not absolute paths are treated as symbolic spec names.
+// Values with issues are skipped.
+func (d *Denylist) set(path string, line int, key string, values []string) []DenylistIssue {
+	canonical, ok := denylistKeys[key]
+	if !ok {
+		return []DenylistIssue{unknownDenylistKey(path, line, key)}
+	}
+
+	var issues []DenylistIssue
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			issues = append(issues, DenylistIssue{path, line, fmt.Sprintf("'%s' contains an empty value", key)})
+			continue
+		}
+		switch canonical {
+		case "files":
+			switch {
+			case filepath.IsAbs(value):
+				if _, err := filepath.Match(value, ""); err != nil {
+					issues = append(issues, DenylistIssue{path, line, fmt.Sprintf("file '%s' is not a valid pattern", value)})
+					continue
+				}
+				d.Files = append(d.Files, value)
+			case componentRegex.MatchString(value):
+				d.Components = append(d.Components, value)
+			default:
+				issues = append(issues, DenylistIssue{path, line, fmt.Sprintf("file '%s' is neither an absolute path nor a spec name", value)})
+			}
+		case "commands":
+			switch {
+			case filepath.IsAbs(strings.Fields(value)[0]):
+				d.Commands = append(d.Commands, value)
+			case componentRegex.MatchString(value):
+				d.Components = append(d.Components, value)
+			default:
+				issues = append(issues, DenylistIssue{path, line, fmt.Sprintf("command '%s' is neither an absolute path nor a spec name", value)})
+			}
+		case "components":
+			if !componentRegex.MatchString(value) {
+				issues = append(issues, DenylistIssue{path, line, fmt.Sprintf("component '%s' is not a spec name", value)})
+				continue
+			}
+			d.Components = append(d.Components, value)
+		case "patterns":
+			d.Patterns = append(d.Patterns, value)
+		case "keywords":
+			d.Keywords = append(d.Keywords, value)
+		}
+	}
+	return issues
+}
+
+func (d *Denylist) merge(other *Denylist) {
+	d.Files = append(d.Files, other.Files...)
+	d.Commands = append(d.Commands, other.Commands...)
+	d.Patterns = append(d.Patterns, other.Patterns...)
+	d.PatternRegexes = append(d.PatternRegexes, other.PatternRegexes...)
+	d.Keywords = append(d.Keywords, other.Keywords...)
+	d.Components = append(d.Components, other.Components...)
+}
+
+// AllowsFile reports whether the file may be collected.
+func (d *Denylist) AllowsFile(path string) bool {
+	for _, denied := range d.Files {
+		if denied == path {
+			return false
+		}
+		if matched, _ := filepath.Match(denied, path); matched {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowsCommand reports whether the output of the command may be collected.
+//
+// The command is denied when either the full command line or its executable is listed.
+func (d *Denylist) AllowsCommand(command []string) bool {
+	line := strings.Join(command, " ")
+	for _, denied := range d.Commands {
+		if denied == line || (len(command) > 0 && denied == command[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter removes lines matching Patterns and PatternRegexes, and replaces Keywords in the data.
+func (d *Denylist) Filter(data []byte) []byte {
+	if !d.filters() {
+		return data
+	}
+
+	var result []string
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		denied := false
+		for _, pattern := range d.Patterns {
+			if strings.Contains(line, pattern) {
+				denied = true
+				break
+			}
+		}
+		for _, regex := range d.PatternRegexes {
+			if denied || regex.MatchString(line) {
+				denied = true
+				break
+			}
+		}
+		if denied {
+			continue
+		}
+		for i, keyword := range d.Keywords {
+			line = strings.ReplaceAll(line, keyword, fmt.Sprintf(KeywordReplacement, i))
+		}
+		result = append(result, line)
+	}
+	return []byte(strings.Join(result, ""))
+}
+
+// filters reports whether Filter changes any data.
+func (d *Denylist) filters() bool {
+	return len(d.Patterns) > 0 || len(d.PatternRegexes) > 0 || len(d.Keywords) > 0
+}
+
+// Enforce removes denied data from the archive directory.
+//
+// Collected files are expected under a `data/` directory with their original path,
+// command output under `insights_commands/`. Patterns and keywords are applied to text files.
+// It returns the number of modified or removed files.
+func (d *Denylist) Enforce(directory string) (int, IError) {
+	deniedCommands := make(map[string]bool)
+	for _, command := range d.Commands {
+		deniedCommands[CommandFileName(strings.Fields(command))] = true
+	}
+
+	changed := 0
+	err := filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		relative, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+
+		if !d.allowsArchivedFile(relative, deniedCommands) {
+			slog.Warn("removing denied file from archive", slog.String("path", relative))
+			changed++
+			return os.Remove(path)
+		}
+
+		if !d.filters() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.IndexByte(data, 0) != -1 || !utf8.Valid(data) {
+			return nil
+		}
+		filtered := d.Filter(data)
+		if bytes.Equal(data, filtered) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		slog.Debug("redacting file in archive", slog.String("path", relative))
+		changed++
+		return os.WriteFile(path, filtered, info.Mode().Perm()|0o600)
+	})
+	if err != nil {
+		return changed, NewError(ErrDenylist, err, "Could not apply denylist to collected data.")
+	}
+	return changed, nil
+}
+
+// allowsArchivedFile maps the file in the archive to its origin and checks it against the denylist.
+func (d *Denylist) allowsArchivedFile(relative string, deniedCommands map[string]bool) bool {
+	parts := strings.Split(filepath.ToSlash(relative), "/")
+	for i, part := range parts {
+		if part == "insights_commands" && i+1 < len(parts) && deniedCommands[parts[i+1]] {
+			return false
+		}
+		if part == "data" && !d.AllowsFile("/"+strings.Join(parts[i+1:], "/")) {
+			return false
+		}
+	}
+	return true
+}
+
+// CommandFileName converts the command to a file name, e.g. `/usr/bin/uname -a` to `uname_-a`.
+func CommandFileName(command []string) string {
+	parts := append([]string{filepath.Base(command[0])}, command[1:]...)
+	name := strings.Join(parts, "_")
+	return strings.NewReplacer("/", ".", " ", "_", "%", "", "{", "", "}", "", "\\n", "").Replace(name)
+}
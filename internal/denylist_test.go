@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestParseDenylist(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Content  string
+		Expected *Denylist
+		Issues   []int
+	}{
+		{"remove.conf", "[remove]\nfiles=/etc/hosts, /etc/*.conf\ncommands=/bin/rpm -qa\npatterns=password\n", &Denylist{
+			Files: []string{"/etc/hosts", "/etc/*.conf"}, Commands: []string{"/bin/rpm -qa"}, Patterns: []string{"password"},
+		}, nil},
+		{"remove.conf", "[remove]\nfiles=etc/hosts\n\n[other]\nkeywords=x\nbroken\n", &Denylist{}, []int{2, 4, 5, 6}},
+		{"remove.conf", "[remove]\ncommands=/bin/ls, ethtool_i\nfiles=cluster_conf\nkeywords=example\n", &Denylist{
+			Commands: []string{"/bin/ls"}, Components: []string{"ethtool_i", "cluster_conf"}, Keywords: []string{"example"},
+		}, nil},
+		{"file-redaction.yaml", "command:\n  - /bin/rpm -qa\n  - ethtool_i\nfile:\n  - /etc/hosts\npatterns:\n  regex:\n    - ab.*cd\n    - \"(\"\n", &Denylist{
+			Files: []string{"/etc/hosts"}, Commands: []string{"/bin/rpm -qa"}, Components: []string{"ethtool_i"},
+			PatternRegexes: []*regexp.Regexp{regexp.MustCompile("ab.*cd")},
+		}, []int{9}},
+		{"file-redaction.yaml", "files:\n  - /etc/hosts\ncomponents:\n  - insights.specs.Specs.hostname\n", &Denylist{
+			Files: []string{"/etc/hosts"}, Components: []string{"insights.specs.Specs.hostname"},
+		}, nil},
+		{"file-redaction.yaml", "files:\n  - /etc/hosts\ncomponents:\n  - hostname\nunknown: []\ncommands: rpm\n", &Denylist{
+			Files: []string{"/etc/hosts"}, Components: []string{"hostname"},
+		}, []int{5, 6}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Content, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), test.Name)
+			if err := os.WriteFile(path, []byte(test.Content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			denylist, issues, err := ParseDenylist(path)
+			if err != nil {
+				t.Fatalf("expected 'nil', got '%v'", err)
+			}
+			if !reflect.DeepEqual(test.Expected, denylist) {
+				t.Errorf("expected '%+v', got '%+v'", test.Expected, denylist)
+			}
+			var lines []int
+			for _, issue := range issues {
+				lines = append(lines, issue.Line)
+			}
+			if !reflect.DeepEqual(test.Issues, lines) {
+				t.Errorf("expected issues on lines '%v', got '%v'", test.Issues, issues)
+			}
+		})
+	}
+}
+
+func TestDenylist_Enforce(t *testing.T) {
+	directory := t.TempDir()
+	files := map[string]string{
+		"data/etc/hosts":                       "127.0.0.1 localhost\n",
+		"data/etc/os-release":                  "NAME=Fedora\nPASSWORD=secret\nTOKEN=42\nID=fedora\n",
+		"data/insights_commands/rpm_-qa":       "bash\n",
+		"data/insights_commands/uname_-a":      "Linux\n",
+		"insights-host/data/etc/cloud/x.conf":  "x\n",
+		"insights-host/data/etc/cloud/x.other": "x\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(directory, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	denylist := &Denylist{
+		Files:          []string{"/etc/hosts", "/etc/cloud/*.conf"},
+		Commands:       []string{"/bin/rpm -qa"},
+		Patterns:       []string{"PASSWORD"},
+		PatternRegexes: []*regexp.Regexp{regexp.MustCompile(`^TOKEN=\d+`)},
+		Keywords:       []string{"Fedora", "Linux"},
+	}
+	if err := os.Chmod(filepath.Join(directory, "data/insights_commands/uname_-a"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := denylist.Enforce(directory)
+	if err != nil {
+		t.Fatalf("expected 'nil', got '%v'", err)
+	}
+	if changed != 5 {
+		t.Errorf("expected '5' changed files, got '%d'", changed)
+	}
+	for _, name := range []string{"data/etc/hosts", "data/insights_commands/rpm_-qa", "insights-host/data/etc/cloud/x.conf"} {
+		if _, err := os.Stat(filepath.Join(directory, name)); err == nil {
+			t.Errorf("expected '%s' to be removed", name)
+		}
+	}
+	rewritten := map[string]string{
+		"data/etc/os-release":             "NAME=keyword0\nID=fedora\n",
+		"data/insights_commands/uname_-a": "keyword1\n",
+	}
+	for name, expected := range rewritten {
+		path := filepath.Join(directory, name)
+		if data, _ := os.ReadFile(path); string(data) != expected {
+			t.Errorf("expected '%s' to contain '%s', got '%s'", name, expected, data)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(directory, "data/insights_commands/uname_-a")); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("expected mode of rewritten file to be kept, got '%v'", info.Mode())
+	}
+}
@@ -10,6 +10,11 @@ var (
 	ErrPermissions   = errors.New("bad permissions")
 	ErrRegistered    = errors.New("host is registered")
 	ErrNotRegistered = errors.New("host is not registered")
+	ErrNoCache       = errors.New("cached file does not exist")
+	ErrHook          = errors.New("hook failed")
+	ErrDenylist      = errors.New("bad denylist")
+	ErrSignature     = errors.New("bad signature")
+	ErrConfiguration = errors.New("bad configuration")
 )
 
 type IError interface {
@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// VerifySignature checks the detached GPG signature of the file against the keyring.
+//
+// The keyring is imported into a temporary GPG home directory,
+// so the verification does not depend on keys trusted by the root user.
+func VerifySignature(path, signature, keyring string) IError {
+	home, err := os.MkdirTemp("", "insights-client-gpg-*")
+	if err != nil {
+		return NewError(ErrSignature, err, "Could not prepare signature verification.")
+	}
+	defer os.RemoveAll(home)
+
+	if err = runGPG(home, "--import", keyring); err != nil {
+		return NewError(ErrSignature, err, "Could not import the signing keys.")
+	}
+	if err = runGPG(home, "--verify", signature, path); err != nil {
+		return NewError(ErrSignature, err, "Signature of the file is not valid.")
+	}
+	slog.Debug("signature verified", slog.String("path", path), slog.String("keyring", keyring))
+	return nil
+}
+
+func runGPG(home string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("gpg", append([]string{"--homedir", home, "--batch", "--no-tty"}, args...)...)
+	cmd.Stderr = &stderr
+	slog.Debug("running gpg", slog.String("command", strings.Join(cmd.Args, " ")))
+	if err := cmd.Run(); err != nil {
+		slog.Error("gpg failed", slog.String("error", err.Error()), slog.String("stderr", stderr.String()))
+		return errors.Join(err, errors.New(stderr.String()))
+	}
+	return nil
+}
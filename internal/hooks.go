@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// HookStage identifies a point of data collection at which hooks are run.
+type HookStage string
+
+const (
+	// HookPreCollect is run before the module collects data into an empty archive directory.
+	HookPreCollect HookStage = "pre-collect"
+	// HookPostCollect is run after the module has collected data into the archive directory.
+	HookPostCollect HookStage = "post-collect"
+	// HookPreUpload is run before the compressed archive is uploaded.
+	HookPreUpload HookStage = "pre-upload"
+	// HookPostUpload is run after the archive has been uploaded. It cannot veto anything.
+	HookPostUpload HookStage = "post-upload"
+)
+
+// HookEnvironment describes the data the hooks are run for.
+type HookEnvironment struct {
+	// Archive is a path to the archive directory or to the compressed archive.
+	Archive     string
+	Module      string
+	ContentType string
+}
+
+// defaultHookPath is used when the client runs without PATH.
+const defaultHookPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// HookRunner runs hooks of data collection stages.
+type HookRunner struct {
+	// Directory contains `<stage>.d/` directories with the hooks.
+	Directory string
+	// Timeout limits the run time of a single hook.
+	Timeout time.Duration
+	// Inherit lists variables passed from the client to the hooks.
+	Inherit []string
+	// Owner is the user ID the hooks and their directories have to be owned by.
+	Owner int
+}
+
+// NewHookRunner creates a runner of the hooks under HooksDirectoryPath, owned by root.
+func NewHookRunner(inherit []string) *HookRunner {
+	return &HookRunner{
+		Directory: HooksDirectoryPath,
+		Timeout:   GetConfiguration().HookTimeout,
+		Inherit:   inherit,
+		Owner:     0,
+	}
+}
+
+// Run runs executables of the stage directory in lexical order.
+//
+// A hook exiting with non-zero code vetoes the operation: the remaining hooks are not run
+// and ErrHook is returned. Hooks and directories that could be modified by other users
+// than the owner are refused the same way. Failures of HookPostUpload hooks are only logged.
+func (r *HookRunner) Run(stage HookStage, environment HookEnvironment) IError {
+	directory := filepath.Join(r.Directory, string(stage)+".d")
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("could not list hooks", slog.String("directory", directory), slog.String("error", err.Error()))
+		}
+		return nil
+	}
+
+	var hooks []string
+	for _, path := range []string{r.Directory, directory} {
+		if err = r.checkOwnership(path); err != nil {
+			return r.reject(stage, path, err)
+		}
+	}
+	for _, entry := range entries {
+		path := filepath.Join(directory, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			slog.Debug("skipping non-executable hook", slog.String("path", path))
+			continue
+		}
+		if err = r.checkOwnership(path); err != nil {
+			return r.reject(stage, path, err)
+		}
+		hooks = append(hooks, path)
+	}
+
+	for _, path := range hooks {
+		if err := r.runHook(path, stage, environment); err != nil {
+			if stage == HookPostUpload {
+				slog.Warn("ignoring failed hook", slog.String("path", path), slog.String("error", err.Error()))
+				continue
+			}
+			return NewError(ErrHook, err, fmt.Sprintf("Hook '%s' rejected the data.", filepath.Base(path)))
+		}
+	}
+	return nil
+}
+
+// checkOwnership ensures the file is owned by the owner and cannot be written by anyone else.
+func (r *HookRunner) checkOwnership(path string) error {
+	return CheckOwnership(path, r.Owner)
+}
+
+// CheckOwnership ensures the file is owned by the user and is neither group- nor world-writable.
+//
+// Files executed or loaded by the client as root are checked this way, e.g. hooks and module manifests.
+func CheckOwnership(path string, owner int) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0o022 != 0 {
+		return fmt.Errorf("'%s' is writable by other users, it has mode %o", path, info.Mode().Perm())
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != owner {
+		return fmt.Errorf("'%s' is owned by %d", path, stat.Uid)
+	}
+	return nil
+}
+
+// reject refuses hooks that cannot be trusted. Only HookPostUpload continues.
+func (r *HookRunner) reject(stage HookStage, path string, err error) IError {
+	slog.Error("refusing insecure hook", slog.String("path", path), slog.String("error", err.Error()))
+	if stage == HookPostUpload {
+		return nil
+	}
+	return NewError(ErrHook, err, fmt.Sprintf("Hook '%s' could be modified by other users than root, refusing to run it.", path))
+}
+
+// environment builds the environment of a hook out of the inherited variables and the hook variables.
+func (r *HookRunner) environment(stage HookStage, environment HookEnvironment) []string {
+	var env []string
+	for _, name := range r.Inherit {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	if !slices.ContainsFunc(env, func(variable string) bool { return strings.HasPrefix(variable, "PATH=") }) {
+		env = append(env, "PATH="+defaultHookPath)
+	}
+	return append(
+		env,
+		"INSIGHTS_HOOK="+string(stage),
+		"INSIGHTS_ARCHIVE="+environment.Archive,
+		"INSIGHTS_MODULE="+environment.Module,
+		"INSIGHTS_CONTENT_TYPE="+environment.ContentType,
+	)
+}
+
+// runHook executes a single hook, logging its output and duration.
+func (r *HookRunner) runHook(path string, stage HookStage, environment HookEnvironment) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.Timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.Env = r.environment(stage, environment)
+
+	slog.Debug("running hook", slog.String("path", path), slog.String("stage", string(stage)))
+	start := time.Now()
+	err := cmd.Run()
+	duration := time.Since(start)
+
+	attributes := []any{
+		slog.String("path", path),
+		slog.String("stage", string(stage)),
+		slog.Duration("duration", duration),
+		slog.String("output", strings.TrimSpace(output.String())),
+	}
+	if err != nil {
+		slog.Error("hook failed", append(attributes, slog.String("error", err.Error()))...)
+		return err
+	}
+	slog.Info("hook finished", attributes...)
+	return nil
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/m-horky/insights-client-next/internal/testutil"
+)
+
+func TestHookRunner_Run(t *testing.T) {
+	t.Setenv("INHERITED", "inherited")
+	t.Setenv("SECRET", "secret")
+	runner := &HookRunner{Directory: t.TempDir(), Timeout: time.Minute, Inherit: []string{"INHERITED"}, Owner: os.Geteuid()}
+	output := filepath.Join(t.TempDir(), "output")
+
+	hooks := map[string]string{
+		"pre-upload.d/10-record": `echo "$INSIGHTS_HOOK $INSIGHTS_MODULE $INSIGHTS_ARCHIVE $INHERITED ${SECRET:-unset}" >> ` + output,
+		"pre-upload.d/20-reject": `exit 1`,
+		"pre-upload.d/30-never":  `echo never >> ` + output,
+		"post-upload.d/10-fail":  `exit 1`,
+	}
+	for name, content := range hooks {
+		testutil.WriteScript(t, filepath.Join(runner.Directory, name), content)
+	}
+	environment := HookEnvironment{Archive: "/tmp/archive.tar.xz", Module: "advisor"}
+
+	if err := runner.Run(HookPreUpload, environment); err == nil || !err.Is(ErrHook) {
+		t.Errorf("expected '%v', got '%v'", ErrHook, err)
+	}
+	content, _ := os.ReadFile(output)
+	if string(content) != "pre-upload advisor /tmp/archive.tar.xz inherited unset\n" {
+		t.Errorf("expected one hook to record its environment, got '%s'", content)
+	}
+
+	if err := runner.Run(HookPostUpload, environment); err != nil {
+		t.Errorf("expected 'nil', got '%v'", err)
+	}
+	if err := runner.Run(HookPreCollect, environment); err != nil {
+		t.Errorf("expected 'nil', got '%v'", err)
+	}
+}
+
+func TestHookRunner_Run_insecure(t *testing.T) {
+	runner := &HookRunner{Directory: t.TempDir(), Timeout: time.Minute, Owner: os.Geteuid()}
+	output := filepath.Join(t.TempDir(), "output")
+	path := testutil.WriteScript(t, filepath.Join(runner.Directory, "pre-collect.d", "10-record"), "echo run >> "+output)
+	environment := HookEnvironment{Archive: "/tmp/archive", Module: "advisor"}
+
+	tests := []struct {
+		Name  string
+		Path  string
+		Mode  os.FileMode
+		Owner int
+	}{
+		{"writable hook", path, 0o775, runner.Owner},
+		{"writable directory", filepath.Dir(path), 0o777, runner.Owner},
+		{"foreign owner", path, 0o755, runner.Owner + 1},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if err := os.Chmod(test.Path, test.Mode); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = os.Chmod(test.Path, 0o755) })
+			runner := *runner
+			runner.Owner = test.Owner
+
+			if err := runner.Run(HookPreCollect, environment); err == nil || !err.Is(ErrHook) {
+				t.Errorf("expected '%v', got '%v'", ErrHook, err)
+			}
+			if _, err := os.Stat(output); err == nil {
+				t.Error("expected hook not to run")
+			}
+		})
+	}
+}
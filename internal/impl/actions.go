@@ -1,11 +1,17 @@
 package impl
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
 	"time"
 
 	"github.com/briandowns/spinner"
 
 	"github.com/m-horky/insights-client-next/internal"
+	"github.com/m-horky/insights-client-next/modules"
 )
 
 type spin struct {
@@ -15,19 +21,45 @@ type spin struct {
 var Spinner = spin{spin: spinner.New(spinner.CharSets[14], 100*time.Millisecond)}
 
 func (s *spin) Maybe(input *Input, message string) {
-	if input.Format != internal.Human || input.Debug {
+	if input.Format != internal.Human || input.Debug || input.output != nil {
 		return
 	}
 	s.spin.Suffix = " " + message
 	s.spin.Start()
 }
 
-func (s *spin) Stop() {
+// Update changes the message of a running spinner.
+func (s *spin) Update(message string) {
+	if !s.spin.Active() {
+		return
+	}
+	s.spin.Lock()
+	s.spin.Suffix = " " + message
+	s.spin.Unlock()
+}
+
+// Stop stops the spinner started by Maybe.
+//
+// Actions writing into their own output, e.g. modules run by RunModules, do not own the spinner.
+func (s *spin) Stop(input *Input) {
+	if input.output != nil {
+		return
+	}
 	if s.spin.Active() {
 		s.spin.Stop()
 	}
 }
 
+// printJSON formats the value as JSON and prints it.
+func printJSON(value any) internal.IError {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return internal.NewError(nil, err, "Could not format output.")
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
 type InputAction uint
 
 // TODO Explore the possibility to run --group on its own with lightweight collection,
@@ -47,6 +79,23 @@ const (
 	ATestConnection
 	ASupport
 	ASetGroupLocally
+	AShowSystemProfile
+	AShowFacts
+	ASetFacts
+	ASetHostFields
+	ACheckResults
+	AShowResults
+	ADiagnosis
+	AListRemediations
+	ADownloadPlaybook
+	AComplianceStatus
+	ARunModules
+	AListSpecs
+	AValidate
+	AConfigShow
+	AConfigGet
+	AConfigSet
+	AConfigCheck
 )
 
 type Input struct {
@@ -54,6 +103,47 @@ type Input struct {
 	Debug  bool
 	Format internal.Format
 	Args   any
+
+	// output receives messages and warnings of the action instead of the standard outputs.
+	// Spinner is not displayed when it is set.
+	output io.Writer
+}
+
+// stdout returns the writer messages of the action should be printed to.
+func (i *Input) stdout() io.Writer {
+	if i.output != nil {
+		return i.output
+	}
+	return os.Stdout
+}
+
+// stderr returns the writer warnings of the action should be printed to.
+func (i *Input) stderr() io.Writer {
+	if i.output != nil {
+		return i.output
+	}
+	return os.Stderr
+}
+
+// UsesAPI reports whether the action contacts the API and needs the identity of the host.
+//
+// Collections that are not uploaded run offline, unless the module itself needs the API.
+func (i *Input) UsesAPI() bool {
+	switch i.Action {
+	case ANone, AHelp, AListSpecs, AValidate, ASetGroupLocally, AConfigShow, AConfigGet, AConfigSet, AConfigCheck:
+		return false
+	case ARunModule:
+		return i.Args.(ARunModuleArgs).usesAPI()
+	case ARunModules:
+		for _, args := range i.Args.(ARunModulesArgs).Modules {
+			if args.usesAPI() {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
 }
 
 type ARegisterArgs struct {
@@ -83,6 +173,30 @@ type ARunModuleArgs struct {
 	StopAtFile bool
 	// KeepArchive performs collection, compression and upload, but not deletion of an archive.
 	StopAtCleanup bool
+	// Offline prevents contacting the API for anything but the collection itself.
+	Offline bool
+}
+
+// uploads reports whether the collected archive is sent to the API.
+func (a ARunModuleArgs) uploads() bool {
+	return !a.StopAtDir && !a.StopAtFile && !a.Offline
+}
+
+// usesAPI reports whether the module run contacts the API.
+//
+// Compliance fetches the policies of the host even when the archive is not uploaded.
+// Module commands other than the collection run locally.
+func (a ARunModuleArgs) usesAPI() bool {
+	module, ok := modules.GetModuleByCommand(a.Command)
+	if !ok || !reflect.DeepEqual(a.Command, module.ArchiveCommandName) {
+		return false
+	}
+	return module.Name == modules.GetComplianceModule().Name || a.uploads()
+}
+
+type ARunModulesArgs struct {
+	// Modules are collected concurrently, each into its own archive.
+	Modules []ARunModuleArgs
 }
 
 type AUploadLocalArchiveArgs struct {
@@ -93,3 +207,39 @@ type AUploadLocalArchiveArgs struct {
 type ASetGroupLocallyArgs struct {
 	Name string
 }
+
+type ASetHostFieldsArgs struct {
+	// Fields maps Inventory field names to their new values.
+	Fields map[string]string
+}
+
+type AShowFactsArgs struct {
+	Namespace string
+}
+
+type ASetFactsArgs struct {
+	Namespace string
+	Facts     map[string]string
+}
+
+type AShowResultsArgs struct {
+	// SortBy is one of 'severity', 'category', 'rule' or 'date'.
+	SortBy   string
+	Severity string
+	Category string
+}
+
+type ADownloadPlaybookArgs struct {
+	RemediationID string
+	// Path is a file the playbook is saved to. If empty, it is saved to the working directory.
+	Path string
+}
+
+type AConfigGetArgs struct {
+	Key string
+}
+
+type AConfigSetArgs struct {
+	Key   string
+	Value string
+}
@@ -0,0 +1,174 @@
+package impl
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/m-horky/insights-client-next/api/advisor"
+	"github.com/m-horky/insights-client-next/api/inventory"
+	"github.com/m-horky/insights-client-next/internal"
+)
+
+// getAdvisorReport downloads the Advisor report of the host.
+//
+// The report is cached; if it has not changed since the last download, the cached copy is used.
+// When Inventory or Advisor cannot be contacted, the cached copy is used as well.
+func getAdvisorReport(input *Input) ([]advisor.Hit, internal.IError) {
+	cached, cacheErr := internal.ReadCachedFile(internal.AdvisorReportCachePath)
+	etag := ""
+	if cacheErr == nil {
+		etag = cached.ETag
+	}
+
+	Spinner.Maybe(input, "Fetching host record from Inventory.")
+	host, err := getCurrentInventoryHost()
+	Spinner.Stop(input)
+	var report *advisor.Report
+	if err == nil {
+		Spinner.Maybe(input, "Downloading Advisor report.")
+		report, err = advisor.GetReport(host.InsightsInventoryID, etag)
+		Spinner.Stop(input)
+	}
+	// unregistered hosts have no report, even when a stale one is cached
+	if err != nil && (cacheErr != nil || err.Is(inventory.ErrNoHost)) {
+		return nil, err
+	}
+	if err != nil {
+		slog.Warn("using cached Advisor report", slog.String("error", err.Error()))
+		_, _ = fmt.Fprintln(os.Stderr, "Warning: Advisor could not be contacted, displaying cached report.")
+		report = &advisor.Report{ETag: etag, NotModified: true}
+	}
+
+	if report.NotModified {
+		var hits []advisor.Hit
+		if err := json.Unmarshal(cached.Data, &hits); err != nil {
+			return nil, internal.NewError(nil, err, "Could not parse cached Advisor report.")
+		}
+		return hits, nil
+	}
+
+	data, jsonErr := json.Marshal(report.Hits)
+	if jsonErr != nil {
+		return nil, internal.NewError(nil, jsonErr, "Could not save Advisor report.")
+	}
+	cached = &internal.CachedFile{Path: internal.AdvisorReportCachePath, Data: data, ETag: report.ETag}
+	if err := cached.Write(); err != nil {
+		slog.Warn("could not cache Advisor report", slog.String("error", err.Error()))
+	}
+	return report.Hits, nil
+}
+
+// RunCheckResults downloads the Advisor report.
+func RunCheckResults(input *Input) internal.IError {
+	hits, err := getAdvisorReport(input)
+	if err != nil {
+		return err
+	}
+
+	if input.Format == internal.JSON {
+		return printJSON(map[string]int{"recommendations": len(hits)})
+	}
+	fmt.Printf("Advisor report has been downloaded. There are %d recommendations for this host.\n", len(hits))
+	return nil
+}
+
+// RunShowResults downloads and displays the Advisor report.
+func RunShowResults(input *Input) internal.IError {
+	args := input.Args.(AShowResultsArgs)
+
+	var severity advisor.Severity
+	if args.Severity != "" {
+		parsed, parseErr := advisor.ParseSeverity(args.Severity)
+		if parseErr != nil {
+			return internal.NewError(internal.ErrInput, parseErr, fmt.Sprintf("Unknown severity '%s'.", args.Severity))
+		}
+		severity = parsed
+	}
+	if args.SortBy == "" {
+		args.SortBy = "severity"
+	}
+	less, ok := advisorHitSorters[args.SortBy]
+	if !ok {
+		return internal.NewError(internal.ErrInput, nil, fmt.Sprintf("Results cannot be sorted by '%s'.", args.SortBy))
+	}
+
+	hits, err := getAdvisorReport(input)
+	if err != nil {
+		return err
+	}
+
+	var filtered []advisor.Hit
+	for _, hit := range hits {
+		if severity != 0 && hit.Rule.Severity() != severity {
+			continue
+		}
+		if args.Category != "" && !strings.EqualFold(hit.Rule.Category.Name, args.Category) {
+			continue
+		}
+		filtered = append(filtered, hit)
+	}
+	sort.SliceStable(filtered, func(i, j int) bool { return less(filtered[i], filtered[j]) })
+
+	if input.Format == internal.JSON {
+		if filtered == nil {
+			filtered = []advisor.Hit{}
+		}
+		return printJSON(filtered)
+	}
+
+	if len(filtered) == 0 {
+		fmt.Println("There are no recommendations for this host.")
+		return nil
+	}
+	printAdvisorHits(filtered)
+	return nil
+}
+
+// advisorHitSorters defines the ways results can be sorted in.
+var advisorHitSorters = map[string]func(a, b advisor.Hit) bool{
+	"severity": func(a, b advisor.Hit) bool {
+		return a.Rule.TotalRisk > b.Rule.TotalRisk
+	},
+	"category": func(a, b advisor.Hit) bool {
+		return a.Rule.Category.Name < b.Rule.Category.Name
+	},
+	"rule": func(a, b advisor.Hit) bool {
+		return a.Rule.RuleID < b.Rule.RuleID
+	},
+	"date": func(a, b advisor.Hit) bool {
+		return a.ImpactedDate.After(b.ImpactedDate)
+	},
+}
+
+// printAdvisorHits renders the table of recommendations, followed by their resolutions.
+func printAdvisorHits(hits []advisor.Hit) {
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "#\tSEVERITY\tCATEGORY\tDESCRIPTION")
+	for i, hit := range hits {
+		_, _ = fmt.Fprintf(
+			table, "%d\t%s\t%s\t%s\n",
+			i+1, hit.Rule.Severity(), hit.Rule.Category.Name, hit.Rule.Description,
+		)
+	}
+	_ = table.Flush()
+
+	for i, hit := range hits {
+		fmt.Printf("\n[%d] %s\n", i+1, hit.Rule.Description)
+		fmt.Printf("* Rule:           %s\n", hit.Rule.RuleID)
+		fmt.Printf("* Total risk:     %d (%s)\n", hit.Rule.TotalRisk, hit.Rule.Severity())
+		if hit.Rule.RebootRequired {
+			fmt.Println("* Reboot:         required")
+		}
+		if url := hit.KnowledgeBaseURL(); url != "" {
+			fmt.Printf("* Knowledge base: %s\n", url)
+		}
+		if resolution := strings.TrimSpace(hit.Resolution.Resolution); resolution != "" {
+			fmt.Printf("* Resolution:     %s\n", strings.ReplaceAll(resolution, "\n", "\n                  "))
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package impl
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/m-horky/insights-client-next/api/compliance"
+	"github.com/m-horky/insights-client-next/api/inventory"
+	"github.com/m-horky/insights-client-next/internal"
+	"github.com/m-horky/insights-client-next/modules"
+)
+
+// compliancePolicyStatus is displayed by RunComplianceStatus.
+type compliancePolicyStatus struct {
+	Policy     compliance.Policy      `json:"policy"`
+	Tailorings []compliance.Tailoring `json:"tailorings"`
+	Result     *compliance.TestResult `json:"result"`
+}
+
+// RunComplianceStatus calls Compliance API.
+func RunComplianceStatus(input *Input) internal.IError {
+	Spinner.Maybe(input, "Fetching host record from Inventory.")
+	host, err := getCurrentInventoryHost()
+	Spinner.Stop(input)
+	if err != nil {
+		return err
+	}
+
+	Spinner.Maybe(input, "Fetching policies from Compliance.")
+	policies, err := compliance.GetPolicies(host.InsightsInventoryID)
+	Spinner.Stop(input)
+	if err != nil {
+		return err
+	}
+
+	statuses := make([]compliancePolicyStatus, 0, len(policies))
+	for _, policy := range policies {
+		status := compliancePolicyStatus{Policy: policy}
+
+		Spinner.Maybe(input, fmt.Sprintf("Fetching status of policy '%s'.", policy.Title))
+		status.Tailorings, err = compliance.GetTailorings(policy.ID)
+		if err == nil {
+			status.Result, err = compliance.GetLatestTestResult(host.InsightsInventoryID, policy.ID)
+		}
+		Spinner.Stop(input)
+		if err != nil && !err.Is(compliance.ErrNoResult) {
+			return err
+		}
+		statuses = append(statuses, status)
+	}
+
+	if input.Format == internal.JSON {
+		return printJSON(statuses)
+	}
+
+	if len(statuses) == 0 {
+		fmt.Println("This host is not assigned to any compliance policy.")
+		return nil
+	}
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "POLICY\tPROFILE\tTAILORINGS\tSCORE\tCOMPLIANT\tLAST SCAN")
+	for _, status := range statuses {
+		score, compliant, scanned := "-", "-", "never"
+		if status.Result != nil {
+			score = fmt.Sprintf("%.1f%%", status.Result.Score)
+			compliant = "no"
+			if status.Result.Compliant {
+				compliant = "yes"
+			}
+			scanned = status.Result.EndTime.Format("2006-01-02 15:04")
+		}
+		_, _ = fmt.Fprintf(
+			table, "%s\t%s\t%d\t%s\t%s\t%s\n",
+			status.Policy.Title, status.Policy.RefID, len(status.Tailorings), score, compliant, scanned,
+		)
+	}
+	_ = table.Flush()
+	return nil
+}
+
+// runComplianceCollection runs the module collection once for each assigned policy.
+func runComplianceCollection(input *Input, host *inventory.Host, module *modules.Module, args ARunModuleArgs) internal.IError {
+	Spinner.Maybe(input, "Fetching policies from Compliance.")
+	policies, err := compliance.GetPolicies(host.InsightsInventoryID)
+	Spinner.Stop(input)
+	if err != nil {
+		return err
+	}
+	if len(policies) == 0 {
+		return internal.NewError(nil, nil, "This host is not assigned to any compliance policy.")
+	}
+
+	// The system profile is used to pick the tailoring of the host's OS minor version.
+	Spinner.Maybe(input, "Fetching system profile from Inventory.")
+	profile, err := inventory.GetSystemProfile(host.InsightsInventoryID)
+	Spinner.Stop(input)
+	if err != nil {
+		return err
+	}
+
+	for _, policy := range policies {
+		slog.Debug("running compliance collection", slog.String("policy", policy.ID), slog.String("profile", policy.RefID))
+
+		policyArgs := args
+		policyArgs.Options = append([]string{}, args.Options...)
+		policyArgs.Options = append(policyArgs.Options, fmt.Sprintf("--policy=%s", policy.ID), fmt.Sprintf("--profile=%s", policy.RefID))
+		if args.ArchiveName != "" {
+			policyArgs.ArchiveName = fmt.Sprintf("%s-%s", args.ArchiveName, policy.ID)
+		}
+
+		tailoringFile, err := downloadComplianceTailoring(input, policy, profile.OperatingSystem.Minor)
+		if err != nil {
+			return err
+		}
+		if tailoringFile != "" {
+			policyArgs.Options = append(policyArgs.Options, fmt.Sprintf("--tailoring-file=%s", tailoringFile))
+		}
+
+		err = runModuleCollection(input, module, policyArgs)
+		if tailoringFile != "" {
+			_ = os.Remove(tailoringFile)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// downloadComplianceTailoring saves the tailoring file of the policy for the OS minor version.
+//
+// Empty path is returned when the policy has no such tailoring.
+func downloadComplianceTailoring(input *Input, policy compliance.Policy, osMinorVersion int) (string, internal.IError) {
+	Spinner.Maybe(input, fmt.Sprintf("Fetching tailoring of policy '%s'.", policy.Title))
+	defer Spinner.Stop(input)
+
+	tailorings, err := compliance.GetTailorings(policy.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, tailoring := range tailorings {
+		if tailoring.OSMinorVersion != osMinorVersion {
+			continue
+		}
+
+		file, fileErr := os.CreateTemp(internal.ArchiveDirectoryParentPath, "tailoring-*.xml")
+		if fileErr != nil {
+			return "", internal.NewError(nil, fileErr, "Could not prepare tailoring file.")
+		}
+		_ = file.Close()
+
+		if err = compliance.DownloadTailoringFile(policy.ID, tailoring.ID, file.Name()); err != nil {
+			_ = os.Remove(file.Name())
+			return "", err
+		}
+		return file.Name(), nil
+	}
+	slog.Debug("policy has no tailoring for this host", slog.String("policy", policy.ID))
+	return "", nil
+}
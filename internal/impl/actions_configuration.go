@@ -0,0 +1,95 @@
+package impl
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/m-horky/insights-client-next/internal"
+)
+
+// RunConfigShow displays the effective configuration along with the origin of each value.
+func RunConfigShow(input *Input) internal.IError {
+	values := internal.GetConfigurationValues()
+
+	if input.Format == internal.JSON {
+		return printJSON(values)
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "KEY\tVALUE\tORIGIN")
+	for _, value := range values {
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\n", value.Key, value.Value, value.Origin)
+	}
+	_ = table.Flush()
+	return nil
+}
+
+// RunConfigGet displays the effective value of a single configuration key.
+func RunConfigGet(input *Input) internal.IError {
+	args := input.Args.(AConfigGetArgs)
+
+	value, err := internal.GetConfigurationValue(args.Key)
+	if err != nil {
+		return err
+	}
+
+	if input.Format == internal.JSON {
+		return printJSON(value)
+	}
+	fmt.Println(value.Value)
+	return nil
+}
+
+// RunConfigSet stores the configuration value in a drop-in file.
+//
+// The effective value is displayed with passwords redacted, so credentials do not end up in logs.
+func RunConfigSet(input *Input) internal.IError {
+	args := input.Args.(AConfigSetArgs)
+
+	value, err := internal.SetConfigurationValue(args.Key, args.Value)
+	if err != nil {
+		return err
+	}
+
+	if input.Format == internal.JSON {
+		return printJSON(value)
+	}
+	fmt.Printf("Configuration key '%s' has been set in '%s'.\n", value.Key, value.Origin)
+	return nil
+}
+
+// RunConfigCheck reports problems of the configuration.
+//
+// Warnings, e.g. about unknown or deprecated keys, do not make the configuration invalid.
+// Keys of the legacy client that are not supported anymore are errors.
+func RunConfigCheck(input *Input) internal.IError {
+	issues := internal.CheckConfiguration()
+	errors := 0
+	for _, issue := range issues {
+		if issue.Severity == internal.SeverityError {
+			errors++
+		}
+	}
+
+	if input.Format == internal.JSON {
+		if issues == nil {
+			issues = []internal.ConfigurationIssue{}
+		}
+		if err := printJSON(map[string]any{"valid": errors == 0, "issues": issues}); err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range issues {
+			fmt.Println(issue.String())
+		}
+	}
+
+	if errors > 0 {
+		return internal.NewError(internal.ErrConfiguration, nil, fmt.Sprintf("Configuration contains %d errors.", errors))
+	}
+	if input.Format != internal.JSON {
+		fmt.Println("Configuration is valid.")
+	}
+	return nil
+}
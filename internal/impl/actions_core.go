@@ -0,0 +1,91 @@
+package impl
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/m-horky/insights-client-next/api/updates"
+	"github.com/m-horky/insights-client-next/internal"
+	"github.com/m-horky/insights-client-next/modules"
+)
+
+// updateCore downloads the newest Core egg when it is enabled by the configuration.
+//
+// The egg is only stored as the newest one when its signature is valid.
+// Failures are logged; the collection continues with the eggs that are already present.
+func updateCore(input *Input) {
+	config := internal.GetConfiguration()
+	if !config.AutoUpdate || os.Getenv("EGG") != "" {
+		return
+	}
+
+	Spinner.Maybe(input, "Checking for Core updates.")
+	defer Spinner.Stop(input)
+
+	channel, err := updates.GetChannel("insights-core")
+	if err != nil {
+		slog.Warn("could not check for Core updates", slog.String("error", err.Error()))
+		return
+	}
+
+	// The discarded egg is not downloaded again until a different one is published.
+	etag := modules.SystemEggs().RejectedETag()
+	if cached, err := internal.ReadCachedFile(modules.NewestEggPath); err == nil {
+		etag = cached.ETag
+	}
+	egg, err := updates.GetEgg(channel, etag)
+	if err != nil {
+		slog.Warn("could not download Core", slog.String("error", err.Error()))
+		return
+	}
+	if egg.NotModified {
+		return
+	}
+
+	if err := stageEgg(egg, config.GPGKeyring); err != nil {
+		slog.Error("could not stage Core", slog.String("error", err.Error()))
+		return
+	}
+	slog.Info("Core updated", slog.String("path", modules.NewestEggPath), slog.String("etag", egg.ETag))
+}
+
+// stageEgg verifies the egg and stores it as the newest one.
+func stageEgg(egg *updates.Egg, keyring string) internal.IError {
+	directory := filepath.Dir(modules.NewestEggPath)
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return internal.NewError(nil, err, "Could not create Core directory.")
+	}
+	staging, err := os.MkdirTemp(directory, "staging-*")
+	if err != nil {
+		return internal.NewError(nil, err, "Could not create Core directory.")
+	}
+	defer os.RemoveAll(staging)
+
+	eggPath := filepath.Join(staging, "insights-core.egg")
+	if err = os.WriteFile(eggPath, egg.Data, 0o644); err != nil {
+		return internal.NewError(nil, err, "Could not save Core.")
+	}
+	if err = os.WriteFile(eggPath+".asc", egg.Signature, 0o644); err != nil {
+		return internal.NewError(nil, err, "Could not save Core.")
+	}
+	if err := internal.VerifySignature(eggPath, eggPath+".asc", keyring); err != nil {
+		return err
+	}
+
+	// The egg is moved first, so the metadata never describes an egg that is not in place.
+	if err = os.Rename(eggPath, modules.NewestEggPath); err != nil {
+		return internal.NewError(nil, err, "Could not save Core.")
+	}
+	if err = os.Rename(eggPath+".asc", modules.NewestEggPath+".asc"); err != nil {
+		return internal.NewError(nil, err, "Could not save Core.")
+	}
+	cached := internal.CachedFile{Path: modules.NewestEggPath, ETag: egg.ETag}
+	if err := cached.WriteMetadata(); err != nil {
+		return err
+	}
+	if err = os.Remove(modules.RejectedEggETagPath); err != nil && !os.IsNotExist(err) {
+		slog.Warn("could not remove ETag of discarded egg", slog.String("error", err.Error()))
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package impl
+
+import (
+	"fmt"
+
+	"github.com/m-horky/insights-client-next/internal"
+)
+
+// RunValidate checks the denylist files and reports their problems.
+func RunValidate(input *Input) internal.IError {
+	issues := []internal.DenylistIssue{}
+	for _, path := range []string{internal.DenylistPath, internal.FileRedactionPath} {
+		_, fileIssues, err := internal.ParseDenylist(path)
+		if err != nil {
+			return err
+		}
+		issues = append(issues, fileIssues...)
+	}
+
+	if input.Format == internal.JSON {
+		if err := printJSON(map[string]any{"valid": len(issues) == 0, "issues": issues}); err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range issues {
+			fmt.Println(issue.String())
+		}
+	}
+
+	if len(issues) > 0 {
+		return internal.NewError(internal.ErrDenylist, nil, fmt.Sprintf("Denylist contains %d problems.", len(issues)))
+	}
+	if input.Format != internal.JSON {
+		fmt.Println("Denylist is valid.")
+	}
+	return nil
+}
@@ -51,13 +51,12 @@ func RunRegister(input *Input) internal.IError {
 
 	Spinner.Maybe(input, "Fetching host record from Inventory.")
 	host, err := getCurrentInventoryHost()
-	Spinner.Stop()
+	Spinner.Stop(input)
 	if host != nil {
 		return internal.NewError(internal.ErrRegistered, nil, "This host is already registered.")
 	}
 
-	// TODO Read the location from the rhsm configuration file instead
-	rhsm, err := internal.ReadRHSMIdentity("/etc/pki/consumer/cert.pem")
+	rhsm, err := internal.ReadRHSMIdentity(internal.GetConfiguration().IdentityCertificate)
 	if err != nil {
 		return err
 	}
@@ -66,12 +65,19 @@ func RunRegister(input *Input) internal.IError {
 	if err != nil {
 		return err
 	}
+	if module.UsesCore() {
+		updateCore(input)
+	}
 
 	archiveDirectory, err := modules.CreateArchiveDirectory(internal.ArchiveDirectoryParentPath)
 	if err != nil {
 		return err
 	}
 	defer os.RemoveAll(archiveDirectory)
+	hookEnvironment := internal.HookEnvironment{Archive: archiveDirectory, Module: module.Name, ContentType: module.ArchiveContentType}
+	if err = runHooks(input, internal.HookPreCollect, hookEnvironment); err != nil {
+		return err
+	}
 	Spinner.Maybe(input, "Collecting host data.")
 	var options []string
 	if args.DisplayName != "" {
@@ -80,37 +86,52 @@ func RunRegister(input *Input) internal.IError {
 	if args.AnsibleHostname != "" {
 		options = append(options, fmt.Sprintf("--ansible-host=%s", args.AnsibleHostname))
 	}
-	err = module.Collect(archiveDirectory, options)
-	Spinner.Stop()
+	options = withCollectionRules(input, module, options, false)
+	runOptions, stop := moduleRunOptions(input, module.ArchiveCommandName)
+	err = module.Collect(archiveDirectory, options, runOptions)
+	Spinner.Stop(input)
+	stop()
 	if err != nil {
 		return err
 	}
+	if err = enforceDenylist(input, archiveDirectory); err != nil {
+		return err
+	}
+	if err = runHooks(input, internal.HookPostCollect, hookEnvironment); err != nil {
+		return err
+	}
 
 	Spinner.Maybe(input, "Compressing host data.")
 	archiveFile, err := internal.CompressDirectoryToPath(archiveDirectory, archiveDirectory+".tar.xz")
-	Spinner.Stop()
+	Spinner.Stop(input)
 	if err != nil {
 		return err
 	}
 	defer os.Remove(archiveFile)
 
+	hookEnvironment = internal.HookEnvironment{Archive: archiveFile, Module: module.Name, ContentType: module.ArchiveContentType + "+tar.xz"}
+	if err = runHooks(input, internal.HookPreUpload, hookEnvironment); err != nil {
+		return err
+	}
 	Spinner.Maybe(input, "Uploading data archive.")
 	_, err = ingress.UploadArchive(
 		ingress.Archive{Path: archiveFile, ContentType: module.ArchiveContentType + "+tar.xz"},
 	)
-	Spinner.Stop()
+	Spinner.Stop(input)
 	if err != nil {
 		return err
 	}
+	_ = runHooks(input, internal.HookPostUpload, hookEnvironment)
 
 	if err = registerLocally(rhsm); err != nil {
 		return err
 	}
 
-	fmt.Printf(
-		"This host is now registered. Visit %s to see the Red Hat Insights console.\n",
-		"https://console.redhat.com/insights/",
-	)
+	console := "https://console.redhat.com/insights/"
+	if config := internal.GetConfiguration(); config.Satellite {
+		console = fmt.Sprintf("https://%s/redhat_access/insights", config.APIHost)
+	}
+	fmt.Printf("This host is now registered. Visit %s to see the Red Hat Insights console.\n", console)
 	return nil
 }
 
@@ -118,7 +139,7 @@ func RunRegister(input *Input) internal.IError {
 func RunUnregister(input *Input) internal.IError {
 	Spinner.Maybe(input, "Fetching host record from Inventory.")
 	host, err := getCurrentInventoryHost()
-	Spinner.Stop()
+	Spinner.Stop(input)
 	if err != nil {
 		return err
 	}
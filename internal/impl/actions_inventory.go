@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/m-horky/insights-client-next/api"
 	"github.com/m-horky/insights-client-next/api/inventory"
 	"github.com/m-horky/insights-client-next/internal"
 )
@@ -31,7 +34,7 @@ func getCurrentInventoryHost() (*inventory.Host, internal.IError) {
 func RunCheckIn(input *Input) internal.IError {
 	Spinner.Maybe(input, "Fetching host record from Inventory.")
 	_, err := getCurrentInventoryHost()
-	Spinner.Stop()
+	Spinner.Stop(input)
 	if err != nil && err.Is(inventory.ErrNoHost) {
 		return err
 	}
@@ -41,7 +44,7 @@ func RunCheckIn(input *Input) internal.IError {
 
 	Spinner.Maybe(input, "Updating host record in Inventory.")
 	err = inventory.CheckIn()
-	Spinner.Stop()
+	Spinner.Stop(input)
 	if err != nil && err.Is(inventory.ErrNoHost) {
 		fmt.Println("This host is not registered")
 	}
@@ -53,45 +56,144 @@ func RunCheckIn(input *Input) internal.IError {
 	return nil
 }
 
-// RunStatus calls Inventory API.
+// hostStatus is the registration of the host displayed by RunStatus.
+type hostStatus struct {
+	Registered          bool                 `json:"registered"`
+	AuthMethod          string               `json:"auth_method"`
+	Identity            *api.CertificateInfo `json:"identity,omitempty"`
+	InsightsClientID    string               `json:"insights_client_id,omitempty"`
+	InsightsInventoryID string               `json:"insights_inventory_id,omitempty"`
+	OrganizationID      string               `json:"org_id,omitempty"`
+	UploadTarget        string               `json:"upload_target"`
+}
+
+// RunStatus displays the identity of the host and calls Inventory API.
+//
+// A host without the identity certificate is not registered; Inventory is not contacted.
 func RunStatus(input *Input) internal.IError {
-	Spinner.Maybe(input, "Fetching host record from Inventory.")
-	host, err := getCurrentInventoryHost()
-	Spinner.Stop()
-	if err != nil && err.Is(inventory.ErrNoHost) {
-		fmt.Println("This host is not registered.")
-		return nil
+	config := internal.GetConfiguration()
+	status := hostStatus{AuthMethod: config.AuthMethod, UploadTarget: uploadTarget()}
+
+	var err internal.IError
+	if config.AuthMethod == "cert" {
+		status.Identity, err = inspectIdentity(config.IdentityCertificate, config.IdentityKey)
 	}
-	if err != nil {
+	if err == nil && (config.AuthMethod != "cert" || status.Identity != nil) {
+		Spinner.Maybe(input, "Fetching host record from Inventory.")
+		var host *inventory.Host
+		host, err = getCurrentInventoryHost()
+		Spinner.Stop(input)
+		if err == nil {
+			status.Registered = true
+			status.InsightsClientID = host.InsightsClientID
+			status.InsightsInventoryID = host.InsightsInventoryID
+			status.OrganizationID = host.OrganizationID
+		} else if err.Is(inventory.ErrNoHost) {
+			err = nil
+		}
+	}
+
+	if input.Format == internal.JSON {
+		if jsonErr := printJSON(status); jsonErr != nil {
+			return jsonErr
+		}
 		return err
 	}
 
+	if status.Identity != nil {
+		printIdentityStatus(status.Identity, time.Duration(config.CertificateWarningDays)*24*time.Hour)
+	} else if config.AuthMethod != "cert" {
+		fmt.Printf("Host authenticates with '%s' method.\n", config.AuthMethod)
+	}
+	if err != nil {
+		return err
+	}
+	if !status.Registered {
+		fmt.Println("This host is not registered.")
+		fmt.Printf("* Upload target:         %s\n", status.UploadTarget)
+		return nil
+	}
 	fmt.Println("This host is registered.")
-	fmt.Printf("* Insights Client ID:    %s\n", host.InsightsClientID)
-	fmt.Printf("* Insights Inventory ID: %s\n", host.InsightsInventoryID)
-	fmt.Printf("* Organization ID:       %s\n", host.OrganizationID)
+	fmt.Printf("* Insights Client ID:    %s\n", status.InsightsClientID)
+	fmt.Printf("* Insights Inventory ID: %s\n", status.InsightsInventoryID)
+	fmt.Printf("* Organization ID:       %s\n", status.OrganizationID)
+	fmt.Printf("* Upload target:         %s\n", status.UploadTarget)
 	return nil
 }
 
-// RunSetDisplayName calls Inventory API.
-func RunSetDisplayName(input *Input) internal.IError {
-	args := input.Args.(ASetDisplayNameArgs)
+// inspectIdentity reads the identity certificate of the host.
+//
+// Missing certificate means the host is not registered, nil is returned.
+// The certificate is returned together with an error when it cannot be used.
+func inspectIdentity(certPath, keyPath string) (*api.CertificateInfo, internal.IError) {
+	if _, err := os.Stat(certPath); os.IsNotExist(err) {
+		slog.Debug("host is not registered, identity certificate does not exist", slog.String("path", certPath))
+		return nil, nil
+	}
+	certificate, err := api.InspectCertificate(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+	if err = certificate.Validate(time.Now()); err != nil {
+		return certificate, err
+	}
+	return certificate, nil
+}
 
-	if args.Name == "" {
-		return internal.NewError(internal.ErrInput, nil, "Display name cannot be empty.")
+// printIdentityStatus displays the identity certificate of the host.
+//
+// A notice is displayed when the certificate expires within the warning period.
+func printIdentityStatus(certificate *api.CertificateInfo, warning time.Duration) {
+	key := "does not match the certificate"
+	if certificate.KeyMatches {
+		key = "matches the certificate"
+	}
+	fmt.Printf("Identity certificate '%s':\n", certificate.Path)
+	fmt.Printf("* Subject:               %s\n", certificate.Subject)
+	fmt.Printf("* Issuer:                %s\n", certificate.Issuer)
+	fmt.Printf("* Serial number:         %s\n", certificate.Serial)
+	fmt.Printf("* Valid:                 %s to %s\n", certificate.NotBefore.Format(time.DateTime), certificate.NotAfter.Format(time.DateTime))
+	fmt.Printf("* Key:                   %s, %s\n", certificate.KeyType, key)
+
+	now := time.Now()
+	if certificate.ExpiresWithin(now, warning) && now.Before(certificate.NotAfter) {
+		fmt.Printf("Identity certificate expires in %d days.\n", int(certificate.NotAfter.Sub(now).Hours()/24))
+	}
+}
+
+// uploadTarget describes whether the host talks to Red Hat directly or through Satellite.
+func uploadTarget() string {
+	config := internal.GetConfiguration()
+	if config.Satellite {
+		return fmt.Sprintf("Red Hat Satellite (%s)", config.APIHost)
 	}
+	return fmt.Sprintf("Red Hat (%s)", config.APIHost)
+}
 
+// updateHost sends the patch to Inventory.
+func updateHost(input *Input, patch inventory.HostPatch) internal.IError {
 	Spinner.Maybe(input, "Fetching host record from Inventory.")
 	host, err := getCurrentInventoryHost()
-	Spinner.Stop()
+	Spinner.Stop(input)
 	if err != nil {
 		return err
 	}
 
 	Spinner.Maybe(input, "Updating host record in Inventory.")
-	err = inventory.UpdateDisplayName(host.InsightsInventoryID, args.Name)
-	Spinner.Stop()
-	if err != nil {
+	err = inventory.UpdateHost(host.InsightsInventoryID, patch)
+	Spinner.Stop(input)
+	return err
+}
+
+// RunSetDisplayName calls Inventory API.
+func RunSetDisplayName(input *Input) internal.IError {
+	args := input.Args.(ASetDisplayNameArgs)
+
+	if args.Name == "" {
+		return internal.NewError(internal.ErrInput, nil, "Display name cannot be empty.")
+	}
+
+	if err := updateHost(input, inventory.HostPatch{DisplayName: &args.Name}); err != nil {
 		return err
 	}
 	fmt.Println("Display name was updated.")
@@ -106,19 +208,148 @@ func RunSetAnsibleHostname(input *Input) internal.IError {
 		return internal.NewError(internal.ErrInput, nil, "Ansible hostname cannot be empty.")
 	}
 
+	if err := updateHost(input, inventory.HostPatch{AnsibleHost: &args.Name}); err != nil {
+		return err
+	}
+	fmt.Println("Ansible hostname was updated.")
+	return nil
+}
+
+// RunSetHostFields calls Inventory API.
+func RunSetHostFields(input *Input) internal.IError {
+	args := input.Args.(ASetHostFieldsArgs)
+
+	patch := inventory.HostPatch{}
+	for field, value := range args.Fields {
+		if value == "" {
+			return internal.NewError(internal.ErrInput, nil, fmt.Sprintf("Host field '%s' cannot be empty.", field))
+		}
+		if err := patch.Set(field, value); err != nil {
+			return internal.NewError(
+				internal.ErrInput,
+				err,
+				fmt.Sprintf(
+					"Host field '%s' cannot be updated. Mutable fields are: %s.",
+					field, strings.Join(inventory.HostPatchFields, ", "),
+				),
+			)
+		}
+	}
+	if patch.IsEmpty() {
+		return internal.NewError(internal.ErrInput, nil, "No host fields were specified.")
+	}
+
+	if err := updateHost(input, patch); err != nil {
+		return err
+	}
+	fmt.Println("Host record was updated.")
+	return nil
+}
+
+// RunShowSystemProfile calls Inventory API.
+func RunShowSystemProfile(input *Input) internal.IError {
+	Spinner.Maybe(input, "Fetching host record from Inventory.")
+	host, err := getCurrentInventoryHost()
+	Spinner.Stop(input)
+	if err != nil {
+		return err
+	}
+
+	Spinner.Maybe(input, "Fetching system profile from Inventory.")
+	profile, err := inventory.GetSystemProfile(host.InsightsInventoryID)
+	Spinner.Stop(input)
+	if err != nil {
+		return err
+	}
+
+	if input.Format == internal.JSON {
+		return printJSON(profile)
+	}
+
+	fmt.Printf("* Architecture:          %s\n", profile.Arch)
+	fmt.Printf(
+		"* Operating system:      %s %d.%d\n",
+		profile.OperatingSystem.Name, profile.OperatingSystem.Major, profile.OperatingSystem.Minor,
+	)
+	fmt.Printf("* Kernel:                %s\n", profile.OSKernelVersion)
+	fmt.Printf("* Infrastructure:        %s %s\n", profile.InfrastructureType, profile.InfrastructureVendor)
+	fmt.Printf("* CPUs:                  %d (%d sockets)\n", profile.NumberOfCPUs, profile.NumberOfSockets)
+	fmt.Printf("* Memory:                %d MiB\n", profile.SystemMemoryBytes/1024/1024)
+	fmt.Printf("* SELinux:               %s\n", profile.SELinuxCurrentMode)
+	fmt.Printf("* Insights Client:       %s\n", profile.InsightsClientVersion)
+	return nil
+}
+
+// RunShowFacts calls Inventory API.
+func RunShowFacts(input *Input) internal.IError {
+	args := input.Args.(AShowFactsArgs)
+
+	if args.Namespace == "" {
+		return internal.NewError(internal.ErrInput, nil, "Facts namespace cannot be empty.")
+	}
+
 	Spinner.Maybe(input, "Fetching host record from Inventory.")
 	host, err := getCurrentInventoryHost()
-	Spinner.Stop()
+	Spinner.Stop(input)
 	if err != nil {
 		return err
 	}
 
+	Spinner.Maybe(input, "Fetching host facts from Inventory.")
+	facts, err := inventory.GetFacts(host.InsightsInventoryID, args.Namespace)
+	Spinner.Stop(input)
+	if err != nil {
+		return err
+	}
+
+	if input.Format == internal.JSON {
+		return printJSON(facts)
+	}
+
+	if len(facts) == 0 {
+		fmt.Printf("There are no facts in namespace '%s'.\n", args.Namespace)
+		return nil
+	}
+	keys := make([]string, 0, len(facts))
+	for key := range facts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Printf("* %s: %v\n", key, facts[key])
+	}
+	return nil
+}
+
+// RunSetFacts calls Inventory API.
+func RunSetFacts(input *Input) internal.IError {
+	args := input.Args.(ASetFactsArgs)
+
+	if args.Namespace == "" {
+		return internal.NewError(internal.ErrInput, nil, "Facts namespace cannot be empty.")
+	}
+	if len(args.Facts) == 0 {
+		return internal.NewError(internal.ErrInput, nil, "No facts were specified.")
+	}
+
+	Spinner.Maybe(input, "Fetching host record from Inventory.")
+	host, err := getCurrentInventoryHost()
+	Spinner.Stop(input)
+	if err != nil {
+		return err
+	}
+
+	facts := make(map[string]any, len(args.Facts))
+	for key, value := range args.Facts {
+		facts[key] = value
+	}
+
 	Spinner.Maybe(input, "Updating host record in Inventory.")
-	err = inventory.UpdateDisplayName(host.InsightsInventoryID, args.Name)
-	Spinner.Stop()
+	err = inventory.UpdateFacts(host.InsightsInventoryID, args.Namespace, facts)
+	Spinner.Stop(input)
 	if err != nil {
 		return err
 	}
-	fmt.Println("Ansible hostname was updated.")
+	fmt.Printf("Facts in namespace '%s' were updated.\n", args.Namespace)
 	return nil
 }
@@ -1,12 +1,20 @@
 package impl
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
+	"syscall"
 
 	"github.com/m-horky/insights-client-next/api/ingress"
+	"github.com/m-horky/insights-client-next/api/inventory"
 	"github.com/m-horky/insights-client-next/internal"
 	"github.com/m-horky/insights-client-next/modules"
 )
@@ -14,39 +22,170 @@ import (
 func RunModule(input *Input) internal.IError {
 	args := input.Args.(ARunModuleArgs)
 
-	Spinner.Maybe(input, "Fetching host record from Inventory.")
-	_, err := getCurrentInventoryHost()
-	Spinner.Stop()
+	host, err := getModuleInventoryHost(input)
 	if err != nil {
 		return err
 	}
+	if module, ok := modules.GetModuleByCommand(args.Command); ok && module.UsesCore() && !args.Offline {
+		updateCore(input)
+	}
+	return runModule(input, host, args)
+}
 
+// getModuleInventoryHost fetches the host record when the modules contact the API.
+//
+// Collections that are not uploaded do not require the host to be registered.
+func getModuleInventoryHost(input *Input) (*inventory.Host, internal.IError) {
+	if !input.UsesAPI() {
+		return nil, nil
+	}
+	Spinner.Maybe(input, "Fetching host record from Inventory.")
+	defer Spinner.Stop(input)
+	return getCurrentInventoryHost()
+}
+
+// runModule runs the module command for the host.
+func runModule(input *Input, host *inventory.Host, args ARunModuleArgs) internal.IError {
 	module, ok := modules.GetModuleByCommand(args.Command)
 	if !ok {
 		return internal.NewError(internal.ErrInput, nil, fmt.Sprintf("No module implements command '%s'.", strings.Join(args.Command, " ")))
 	}
+	// The description may change the content type of the archive.
+	if err := module.Describe(); err != nil {
+		return err
+	}
+	slog.Debug("running module", slog.String("name", module.Name), slog.String("version", module.Version))
+
+	if args.ArchiveParent == "" {
+		args.ArchiveParent = internal.ArchiveDirectoryParentPath
+	}
+
+	if !reflect.DeepEqual(args.Command, module.ArchiveCommandName) {
+		return runModuleCommand(input, module, args)
+	}
+	if module.Name == modules.GetComplianceModule().Name {
+		return runComplianceCollection(input, host, module, args)
+	}
+	args.Options = withCollectionRules(input, module, args.Options, args.Offline)
+	return runModuleCollection(input, module, args)
+}
+
+// moduleRunOptions configures the module execution from the configuration.
+//
+// Progress of the module is displayed in the spinner, unless the action writes into its own output.
+// The returned function has to be called once the module has finished.
+func moduleRunOptions(input *Input, command []string) (*modules.RunOptions, func()) {
+	config := internal.GetConfiguration()
+
+	// Interrupting the client terminates the module gracefully.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	options := &modules.RunOptions{
+		OnProgress: func(event modules.ProgressEvent) {
+			if event.Message != "" && input.output == nil {
+				Spinner.Update(event.Message)
+			}
+		},
+		Context:        ctx,
+		Timeout:        config.GetModuleTimeout(command),
+		KillTimeout:    config.ModuleKillTimeout,
+		DropPrivileges: config.ModuleDropPrivileges,
+		DefaultUser:    config.ModuleUser,
+	}
+	if len(command) > 0 {
+		options.Environment = config.GetModuleEnvironment(command[0])
+	}
+	if config.ModuleScope {
+		options.Limits = &modules.ResourceLimits{
+			Slice:      config.ModuleSlice,
+			CPUQuota:   config.ModuleCPUQuota,
+			MemoryHigh: config.ModuleMemoryHigh,
+			MemoryMax:  config.ModuleMemoryMax,
+			TasksMax:   config.ModuleTasksMax,
+			IOWeight:   config.ModuleIOWeight,
+		}
+	}
+	return options, stop
+}
+
+// enforceDenylist removes data the administrator does not want to be collected.
+//
+// Modules are expected to honor the denylist on their own; this is a second line of defence.
+//
+// Denylist values with issues are reported and skipped, the collection continues.
+func enforceDenylist(input *Input, directory string) internal.IError {
+	denylist, issues, err := internal.ReadDenylist()
+	if err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		_, _ = fmt.Fprintf(input.stderr(), "Warning: Ignoring denylist value: %s\n", issue.String())
+	}
+	changed, err := denylist.Enforce(directory)
+	if err != nil {
+		return err
+	}
+	if changed > 0 {
+		slog.Warn("denylist was enforced on collected data", slog.Int("files", changed))
+	}
+	return nil
+}
+
+// runHooks runs hooks of the stage while displaying the spinner.
+func runHooks(input *Input, stage internal.HookStage, environment internal.HookEnvironment) internal.IError {
+	Spinner.Maybe(input, fmt.Sprintf("Running %s hooks.", stage))
+	defer Spinner.Stop(input)
+	return internal.NewHookRunner(modules.InheritedEnvironment).Run(stage, environment)
+}
+
+// runModuleCommand runs a module command that does not produce an archive.
+//
+// The output of the module is displayed to the user.
+func runModuleCommand(input *Input, module *modules.Module, args ARunModuleArgs) internal.IError {
+	options, stop := moduleRunOptions(input, args.Command)
+	defer stop()
+	options.Stdout = input.stdout()
+	return module.RunCommand(args.Command, args.Options, options)
+}
 
+// runModuleCollection collects, compresses and uploads the module archive.
+func runModuleCollection(input *Input, module *modules.Module, args ARunModuleArgs) internal.IError {
 	archiveDirectory, err := modules.CreateArchiveDirectory(args.ArchiveParent)
 	if err != nil {
 		return err
 	}
+	if args.ArchiveName == "" {
+		args.ArchiveName = filepath.Base(archiveDirectory)
+	}
 	if !args.StopAtDir {
 		defer os.RemoveAll(archiveDirectory)
 	}
+	hookEnvironment := internal.HookEnvironment{Archive: archiveDirectory, Module: module.Name, ContentType: module.ArchiveContentType}
+	if err = runHooks(input, internal.HookPreCollect, hookEnvironment); err != nil {
+		return err
+	}
+	options, stop := moduleRunOptions(input, module.ArchiveCommandName)
 	Spinner.Maybe(input, "Collecting host data.")
-	err = module.Collect(archiveDirectory, args.Options)
-	Spinner.Stop()
+	err = module.Collect(archiveDirectory, args.Options, options)
+	Spinner.Stop(input)
+	stop()
 	if err != nil {
 		return err
 	}
+	if err = enforceDenylist(input, archiveDirectory); err != nil {
+		return err
+	}
+	if err = runHooks(input, internal.HookPostCollect, hookEnvironment); err != nil {
+		return err
+	}
 	if args.StopAtDir {
-		fmt.Printf("Data have been collected to '%s'. Its content type is %s.\n", archiveDirectory, module.ArchiveContentType)
+		_, _ = fmt.Fprintf(input.stdout(), "Data have been collected to '%s'. Its content type is %s.\n", archiveDirectory, module.ArchiveContentType)
 		return nil
 	}
 
 	Spinner.Maybe(input, "Compressing host data.")
 	archiveFile, err := internal.CompressDirectoryToPath(archiveDirectory, filepath.Join(args.ArchiveParent, args.ArchiveName+".tar.xz"))
-	Spinner.Stop()
+	Spinner.Stop(input)
 	if err != nil {
 		return err
 	}
@@ -54,23 +193,124 @@ func RunModule(input *Input) internal.IError {
 		defer os.Remove(archiveFile)
 	}
 	if args.StopAtFile {
-		fmt.Printf("Data have been collected to '%s'. Its content type is '%s+tar.xz'.\n", archiveFile, module.ArchiveContentType)
+		_, _ = fmt.Fprintf(input.stdout(), "Data have been collected to '%s'. Its content type is '%s+tar.xz'.\n", archiveFile, module.ArchiveContentType)
 		return nil
 	}
 
+	hookEnvironment = internal.HookEnvironment{Archive: archiveFile, Module: module.Name, ContentType: module.ArchiveContentType + "+tar.xz"}
+	if err = runHooks(input, internal.HookPreUpload, hookEnvironment); err != nil {
+		return err
+	}
 	Spinner.Maybe(input, "Uploading data archive.")
 	_, err = ingress.UploadArchive(
 		ingress.Archive{Path: archiveFile, ContentType: module.ArchiveContentType + "+tar.xz"},
 	)
-	Spinner.Stop()
+	Spinner.Stop(input)
 	if err != nil {
 		return err
 	}
+	_ = runHooks(input, internal.HookPostUpload, hookEnvironment)
 
 	if args.StopAtCleanup {
-		fmt.Printf("Data archive has been uploaded, and has been kept at '%s'. Its content type is '%s+tar.xz'.\n", archiveFile, module.ArchiveContentType)
+		_, _ = fmt.Fprintf(input.stdout(), "Data archive has been uploaded, and has been kept at '%s'. Its content type is '%s+tar.xz'.\n", archiveFile, module.ArchiveContentType)
+	} else {
+		_, _ = fmt.Fprintf(input.stdout(), "Data archive has been uploaded.\n")
+	}
+	return nil
+}
+
+// moduleResult is a summary of a single module run by RunModules.
+type moduleResult struct {
+	Module  string `json:"module"`
+	Success bool   `json:"success"`
+	Output  string `json:"output,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// RunModules runs multiple module collections concurrently.
+//
+// The number of modules running at the same time is limited by the configuration.
+// Modules are run to completion even when some of them fail.
+func RunModules(input *Input) internal.IError {
+	args := input.Args.(ARunModulesArgs)
+
+	host, err := getModuleInventoryHost(input)
+	if err != nil {
+		return err
+	}
+
+	for _, moduleArgs := range args.Modules {
+		if module, ok := modules.GetModuleByCommand(moduleArgs.Command); ok && module.UsesCore() && !moduleArgs.Offline {
+			updateCore(input)
+			break
+		}
+	}
+
+	workers := int(internal.GetConfiguration().ModuleWorkers)
+	if workers < 1 {
+		workers = 1
+	}
+	slog.Debug("running modules", slog.Int("count", len(args.Modules)), slog.Int("workers", workers))
+
+	results := make([]moduleResult, len(args.Modules))
+	outputs := make([]bytes.Buffer, len(args.Modules))
+	semaphore := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+
+	Spinner.Maybe(input, fmt.Sprintf("Collecting host data with %d collectors.", len(args.Modules)))
+	for i, moduleArgs := range args.Modules {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			moduleInput := *input
+			moduleInput.output = &outputs[i]
+			results[i].Module = moduleArgs.Command[0]
+			if err := runModule(&moduleInput, host, moduleArgs); err != nil {
+				slog.Error("module collection failed", slog.String("module", results[i].Module), slog.String("error", err.Error()))
+				results[i].Error = err.Human()
+			} else {
+				results[i].Success = true
+			}
+		}()
+	}
+	wg.Wait()
+	Spinner.Stop(input)
+
+	failed := 0
+	for i := range results {
+		results[i].Output = strings.TrimSpace(outputs[i].String())
+		if !results[i].Success {
+			failed++
+		}
+	}
+
+	if input.Format == internal.JSON {
+		if err := printJSON(results); err != nil {
+			return err
+		}
 	} else {
-		fmt.Printf("Data archive has been uploaded.\n")
+		for _, result := range results {
+			if result.Success {
+				fmt.Printf("%s: OK\n", result.Module)
+			} else {
+				fmt.Printf("%s: FAILED\n", result.Module)
+			}
+			for _, line := range strings.Split(result.Output, "\n") {
+				if line != "" {
+					fmt.Printf("  %s\n", line)
+				}
+			}
+			if result.Error != "" {
+				fmt.Printf("  %s\n", result.Error)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return internal.NewError(nil, nil, fmt.Sprintf("%d of %d collectors failed.", failed, len(results)))
 	}
 	return nil
 }
@@ -78,14 +318,19 @@ func RunModule(input *Input) internal.IError {
 func RunUploadLocalArchive(input *Input) internal.IError {
 	args := input.Args.(AUploadLocalArchiveArgs)
 
+	hookEnvironment := internal.HookEnvironment{Archive: args.Path, ContentType: args.ContentType}
+	if err := runHooks(input, internal.HookPreUpload, hookEnvironment); err != nil {
+		return err
+	}
 	Spinner.Maybe(input, "Uploading data archive.")
 	_, err := ingress.UploadArchive(
 		ingress.Archive{Path: args.Path, ContentType: args.ContentType},
 	)
-	Spinner.Stop()
+	Spinner.Stop(input)
 	if err != nil {
 		return err
 	}
+	_ = runHooks(input, internal.HookPostUpload, hookEnvironment)
 
 	fmt.Println("Data archive has been uploaded.")
 	return nil
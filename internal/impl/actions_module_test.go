@@ -0,0 +1,92 @@
+package impl
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/m-horky/insights-client-next/api"
+	"github.com/m-horky/insights-client-next/api/ingress"
+	"github.com/m-horky/insights-client-next/internal"
+	"github.com/m-horky/insights-client-next/internal/testutil"
+	"github.com/m-horky/insights-client-next/modules"
+)
+
+// useDescribingModule registers module 'scanner', whose description changes its content type.
+func useDescribingModule(t *testing.T) {
+	directory := t.TempDir()
+	script := testutil.WriteScript(t, filepath.Join(directory, "scanner"), `if [ "$1" = describe ]; then
+	echo '{"protocol": 1, "content_type": "application/vnd.redhat.scanner.described", "commands": [{"name": ["scanner", "collect"]}]}'
+	exit 0
+fi
+touch "${3#--archive=}/collected"`)
+	manifest := fmt.Sprintf(`name: scanner
+exec: [%s]
+protocol: 1
+commands:
+  - name: [scanner, collect]
+archive_command: [scanner, collect]
+content_type: application/vnd.redhat.scanner.collection
+`, script)
+	if err := os.WriteFile(filepath.Join(directory, "scanner.yaml"), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	directories, owner := modules.ModuleDirectories, modules.ModuleDirectoryOwner
+	t.Cleanup(func() {
+		modules.ModuleDirectories, modules.ModuleDirectoryOwner = directories, owner
+		modules.ClearModules()
+	})
+	modules.ModuleDirectoryOwner = os.Geteuid()
+	modules.ModuleDirectories = []string{directory}
+	modules.ClearModules()
+}
+
+func TestRunModule_describedContentType(t *testing.T) {
+	useDescribingModule(t)
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, header, err := r.FormFile("file"); err == nil {
+			contentType = header.Header.Get("Content-Type")
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"request_id": "x"}`))
+	}))
+	defer server.Close()
+	address, _ := url.Parse(server.URL)
+	ingress.Init(api.NewService(address))
+
+	var output bytes.Buffer
+	input := &Input{Format: internal.JSON, output: &output}
+	args := ARunModuleArgs{Command: []string{"scanner", "collect"}, ArchiveParent: t.TempDir()}
+	if err := runModule(input, nil, args); err != nil {
+		t.Fatalf("expected 'nil', got '%v'", err)
+	}
+	if contentType != "application/vnd.redhat.scanner.described+tar.xz" {
+		t.Errorf("expected described content type, got '%s'", contentType)
+	}
+}
+
+func TestEnforceDenylist_output(t *testing.T) {
+	denylist, redaction := internal.DenylistPath, internal.FileRedactionPath
+	t.Cleanup(func() { internal.DenylistPath, internal.FileRedactionPath = denylist, redaction })
+	internal.DenylistPath = filepath.Join(t.TempDir(), "remove.conf")
+	internal.FileRedactionPath = filepath.Join(t.TempDir(), "file-redaction.yaml")
+	if err := os.WriteFile(internal.DenylistPath, []byte("[remove]\nfiles=etc/hosts\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Modules run by RunModules write their warnings into their own output.
+	var output bytes.Buffer
+	if err := enforceDenylist(&Input{output: &output}, t.TempDir()); err != nil {
+		t.Fatalf("expected 'nil', got '%v'", err)
+	}
+	if !bytes.Contains(output.Bytes(), []byte("Warning: Ignoring denylist value")) {
+		t.Errorf("expected denylist warning in the output, got '%s'", output.String())
+	}
+}
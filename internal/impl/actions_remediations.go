@@ -0,0 +1,151 @@
+package impl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/m-horky/insights-client-next/api/remediations"
+	"github.com/m-horky/insights-client-next/internal"
+)
+
+// RunDiagnosis calls Remediations API.
+func RunDiagnosis(input *Input) internal.IError {
+	Spinner.Maybe(input, "Fetching host record from Inventory.")
+	host, err := getCurrentInventoryHost()
+	Spinner.Stop(input)
+	if err != nil {
+		return err
+	}
+
+	Spinner.Maybe(input, "Fetching diagnosis from Remediations.")
+	diagnosis, err := remediations.GetDiagnosis(host.InsightsInventoryID)
+	Spinner.Stop(input)
+	if err != nil {
+		return err
+	}
+
+	if input.Format == internal.JSON {
+		return printJSON(diagnosis)
+	}
+
+	if len(diagnosis.Insights) == 0 {
+		fmt.Println("There are no remediable issues for this host.")
+		return nil
+	}
+	issues := make([]string, 0, len(diagnosis.Insights))
+	for issue := range diagnosis.Insights {
+		issues = append(issues, issue)
+	}
+	sort.Strings(issues)
+	fmt.Printf("There are %d remediable issues for this host.\n", len(issues))
+	for _, issue := range issues {
+		fmt.Printf("* %s\n", issue)
+		details := diagnosis.Insights[issue]
+		keys := make([]string, 0, len(details))
+		for key := range details {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf("  %s: %v\n", key, details[key])
+		}
+	}
+	return nil
+}
+
+// RunListRemediations calls Remediations API.
+func RunListRemediations(input *Input) internal.IError {
+	Spinner.Maybe(input, "Fetching host record from Inventory.")
+	host, err := getCurrentInventoryHost()
+	Spinner.Stop(input)
+	if err != nil {
+		return err
+	}
+
+	Spinner.Maybe(input, "Fetching plans from Remediations.")
+	plans, err := remediations.GetRemediations(host.InsightsInventoryID)
+	Spinner.Stop(input)
+	if err != nil {
+		return err
+	}
+
+	if input.Format == internal.JSON {
+		if plans == nil {
+			plans = []remediations.Remediation{}
+		}
+		return printJSON(plans)
+	}
+
+	if len(plans) == 0 {
+		fmt.Println("There are no remediation plans for this host.")
+		return nil
+	}
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "ID\tNAME\tISSUES\tSYSTEMS\tREBOOT")
+	for _, plan := range plans {
+		reboot := "no"
+		if plan.NeedsReboot {
+			reboot = "yes"
+		}
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%s\n", plan.ID, plan.Name, plan.IssueCount, plan.SystemCount, reboot)
+	}
+	_ = table.Flush()
+	return nil
+}
+
+// remediationIDRegex matches UUIDs identifying remediation plans.
+var remediationIDRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// playbookPath returns the file the playbook should be saved to.
+//
+// Without an explicit path, the playbook is saved to the working directory; an existing file is not overwritten.
+func playbookPath(args ADownloadPlaybookArgs) (string, internal.IError) {
+	if args.RemediationID == "" {
+		return "", internal.NewError(internal.ErrInput, nil, "Remediation plan ID cannot be empty.")
+	}
+	if !remediationIDRegex.MatchString(args.RemediationID) {
+		return "", internal.NewError(internal.ErrInput, nil, fmt.Sprintf("Remediation plan ID %q is not valid.", args.RemediationID))
+	}
+	if args.Path != "" {
+		return args.Path, nil
+	}
+	path := fmt.Sprintf("remediation-%s.yml", args.RemediationID)
+	if _, err := os.Lstat(path); !errors.Is(err, os.ErrNotExist) {
+		return "", internal.NewError(internal.ErrInput, err, fmt.Sprintf("File '%s' already exists, use '--output-file' to overwrite it.", path))
+	}
+	return path, nil
+}
+
+// RunDownloadPlaybook calls Remediations API.
+func RunDownloadPlaybook(input *Input) internal.IError {
+	args := input.Args.(ADownloadPlaybookArgs)
+
+	path, err := playbookPath(args)
+	if err != nil {
+		return err
+	}
+
+	Spinner.Maybe(input, "Fetching host record from Inventory.")
+	host, err := getCurrentInventoryHost()
+	Spinner.Stop(input)
+	if err != nil {
+		return err
+	}
+
+	Spinner.Maybe(input, "Downloading playbook from Remediations.")
+	err = remediations.DownloadPlaybook(args.RemediationID, host.InsightsInventoryID, path)
+	Spinner.Stop(input)
+	if err != nil {
+		return err
+	}
+
+	if input.Format == internal.JSON {
+		return printJSON(map[string]string{"path": path})
+	}
+	fmt.Printf("Playbook has been saved to '%s'.\n", path)
+	return nil
+}
@@ -0,0 +1,54 @@
+package impl
+
+import (
+	"os"
+	"testing"
+
+	"github.com/m-horky/insights-client-next/internal"
+)
+
+func TestPlaybookPath(t *testing.T) {
+	original, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(original) })
+	existing := "11111111-2222-3333-4444-555555555555"
+	if err := os.WriteFile("remediation-"+existing+".yml", []byte("---\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Args     ADownloadPlaybookArgs
+		Expected string
+		Error    error
+	}{
+		{ADownloadPlaybookArgs{RemediationID: "0f2b4a3e-5a7c-4c1d-9e8f-0123456789ab"}, "remediation-0f2b4a3e-5a7c-4c1d-9e8f-0123456789ab.yml", nil},
+		{ADownloadPlaybookArgs{RemediationID: "0f2b4a3e-5a7c-4c1d-9e8f-0123456789ab", Path: "/tmp/p.yml"}, "/tmp/p.yml", nil},
+		{ADownloadPlaybookArgs{RemediationID: existing, Path: "remediation-" + existing + ".yml"}, "remediation-" + existing + ".yml", nil},
+		{ADownloadPlaybookArgs{RemediationID: existing}, "", internal.ErrInput},
+		{ADownloadPlaybookArgs{RemediationID: ""}, "", internal.ErrInput},
+		{ADownloadPlaybookArgs{RemediationID: "../../etc/cron.d/x"}, "", internal.ErrInput},
+		{ADownloadPlaybookArgs{RemediationID: "x/y", Path: "p.yml"}, "", internal.ErrInput},
+	}
+	for _, test := range tests {
+		t.Run(test.Args.RemediationID, func(t *testing.T) {
+			path, err := playbookPath(test.Args)
+			if test.Error != nil {
+				if err == nil || !err.Is(test.Error) {
+					t.Fatalf("expected '%v', got '%v'", test.Error, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected 'nil', got '%v'", err)
+			}
+			if path != test.Expected {
+				t.Errorf("expected '%s', got '%s'", test.Expected, path)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package impl
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/m-horky/insights-client-next/api"
+	"github.com/m-horky/insights-client-next/api/rules"
+	"github.com/m-horky/insights-client-next/internal"
+	"github.com/m-horky/insights-client-next/modules"
+)
+
+// collectionRulesCache keeps the verified copy of collection rules.
+//
+// To create an instance with the system configuration, use newCollectionRulesCache.
+type collectionRulesCache struct {
+	// Path is where the verified rules are stored.
+	Path string
+	// Keyring contains keys the rules have to be signed with.
+	Keyring string
+	// Download fetches the rules unless they match the validators, see rules.GetRules.
+	Download func(etag, lastModified string) (*rules.Rules, api.IError)
+}
+
+func newCollectionRulesCache() *collectionRulesCache {
+	return &collectionRulesCache{
+		Path:     internal.CollectionRulesCachePath,
+		Keyring:  internal.GetConfiguration().GPGKeyring,
+		Download: rules.GetRules,
+	}
+}
+
+// Get returns a path to the verified collection rules.
+//
+// The rules are cached; if they have not changed since the last download, the cached copy is used.
+// When the API cannot be contacted or `offline` is set, the cached copy is used as well.
+func (c *collectionRulesCache) Get(input *Input, offline bool) (string, internal.IError) {
+	cached, cacheErr := internal.ReadCachedFile(c.Path)
+	if offline {
+		if cacheErr != nil {
+			return "", cacheErr
+		}
+		return cached.Path, nil
+	}
+
+	etag, lastModified := "", ""
+	if cacheErr == nil {
+		etag, lastModified = cached.ETag, cached.LastModified
+	}
+
+	Spinner.Maybe(input, "Downloading collection rules.")
+	downloaded, err := c.Download(etag, lastModified)
+	Spinner.Stop(input)
+	if err != nil && cacheErr != nil {
+		return "", err
+	}
+	if err != nil {
+		slog.Warn("using cached collection rules", slog.String("error", err.Error()))
+		return cached.Path, nil
+	}
+	if downloaded.NotModified {
+		return cached.Path, nil
+	}
+
+	if err := c.store(downloaded); err != nil {
+		if cacheErr != nil {
+			return "", err
+		}
+		slog.Warn("using cached collection rules", slog.String("error", err.Error()))
+		return cached.Path, nil
+	}
+	return c.Path, nil
+}
+
+// withCollectionRules passes the verified collection rules to the module, if it declares modules.CollectionRulesFlag.
+//
+// When the rules are not available, the module is left to obtain them on its own.
+// Rules passed by the user are kept.
+func withCollectionRules(input *Input, module *modules.Module, options []string, offline bool) []string {
+	for _, option := range options {
+		if name, _, _ := strings.Cut(strings.TrimLeft(option, "-"), "="); name == modules.CollectionRulesFlag {
+			return options
+		}
+	}
+	if err := module.Describe(); err != nil || !module.AcceptsFlag(module.ArchiveCommandName, modules.CollectionRulesFlag) {
+		slog.Debug("module does not accept collection rules", slog.String("name", module.Name))
+		return options
+	}
+	path, err := newCollectionRulesCache().Get(input, offline)
+	if err != nil {
+		slog.Warn("collection rules are not available", slog.String("error", err.Error()))
+		return options
+	}
+	return append(append([]string{}, options...), fmt.Sprintf("--%s=%s", modules.CollectionRulesFlag, path))
+}
+
+// store verifies the rules and stores them in the cache.
+func (c *collectionRulesCache) store(downloaded *rules.Rules) internal.IError {
+	directory := filepath.Dir(c.Path)
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return internal.NewError(nil, err, "Could not create cache directory.")
+	}
+	staging, err := os.MkdirTemp(directory, "staging-*")
+	if err != nil {
+		return internal.NewError(nil, err, "Could not create cache directory.")
+	}
+	defer os.RemoveAll(staging)
+
+	path := filepath.Join(staging, rules.RulesFile)
+	if err = os.WriteFile(path, downloaded.Data, 0o600); err != nil {
+		return internal.NewError(nil, err, "Could not save collection rules.")
+	}
+	if err = os.WriteFile(path+".asc", downloaded.Signature, 0o600); err != nil {
+		return internal.NewError(nil, err, "Could not save collection rules.")
+	}
+	if err := internal.VerifySignature(path, path+".asc", c.Keyring); err != nil {
+		return err
+	}
+	if _, err := parseCollectionRules(path); err != nil {
+		return err
+	}
+
+	if err = os.Rename(path+".asc", c.Path+".asc"); err != nil {
+		return internal.NewError(nil, err, "Could not save collection rules.")
+	}
+	cached := internal.CachedFile{
+		Path:         c.Path,
+		Data:         downloaded.Data,
+		ETag:         downloaded.ETag,
+		LastModified: downloaded.LastModified,
+	}
+	return cached.Write()
+}
+
+func parseCollectionRules(path string) (*rules.CollectionRules, internal.IError) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, internal.NewError(internal.ErrNoCache, err, "Could not read collection rules.")
+	}
+	var collectionRules rules.CollectionRules
+	if err = json.Unmarshal(data, &collectionRules); err != nil {
+		return nil, internal.NewError(nil, err, "Collection rules are malformed.")
+	}
+	return &collectionRules, nil
+}
+
+// RunListSpecs displays the specs described by the collection rules.
+//
+// The cached rules are used; they are only downloaded when the cache is empty.
+func RunListSpecs(input *Input) internal.IError {
+	cache := newCollectionRulesCache()
+	path := cache.Path
+	if _, err := os.Stat(path); err != nil {
+		downloaded, err := cache.Get(input, false)
+		if err != nil {
+			return err
+		}
+		path = downloaded
+	}
+	collectionRules, err := parseCollectionRules(path)
+	if err != nil {
+		return err
+	}
+
+	if input.Format == internal.JSON {
+		return printJSON(collectionRules)
+	}
+
+	fmt.Printf("Collection rules version %s.\n\n", collectionRules.Version)
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "TYPE\tNAME\tSOURCE")
+	for _, file := range collectionRules.Files {
+		_, _ = fmt.Fprintf(table, "file\t%s\t%s\n", file.SymbolicName, file.File)
+	}
+	for _, glob := range collectionRules.Globs {
+		_, _ = fmt.Fprintf(table, "glob\t%s\t%s\n", glob.SymbolicName, glob.Glob)
+	}
+	for _, command := range collectionRules.Commands {
+		_, _ = fmt.Fprintf(table, "command\t%s\t%s\n", command.SymbolicName, command.Command)
+	}
+	_ = table.Flush()
+	return nil
+}
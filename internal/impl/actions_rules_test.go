@@ -0,0 +1,158 @@
+package impl
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/m-horky/insights-client-next/api"
+	"github.com/m-horky/insights-client-next/api/rules"
+	"github.com/m-horky/insights-client-next/internal"
+	"github.com/m-horky/insights-client-next/modules"
+)
+
+// newSigningKey creates a GPG key, returning its public keyring and a function signing data with it.
+func newSigningKey(t *testing.T) (string, func(data []byte) []byte) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not available")
+	}
+	home := t.TempDir()
+	gpg := func(args ...string) []byte {
+		t.Helper()
+		cmd := exec.Command("gpg", append([]string{"--homedir", home, "--batch", "--no-tty", "--pinentry-mode", "loopback", "--passphrase", ""}, args...)...)
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("gpg %v failed: %v", args, err)
+		}
+		return output
+	}
+	t.Cleanup(func() { _ = exec.Command("gpgconf", "--homedir", home, "--kill", "all").Run() })
+
+	gpg("--quick-generate-key", "Test <test@example.com>", "ed25519", "sign", "never")
+	keyring := filepath.Join(t.TempDir(), "keyring.gpg")
+	if err := os.WriteFile(keyring, gpg("--export", "test@example.com"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return keyring, func(data []byte) []byte {
+		path := filepath.Join(t.TempDir(), "data")
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return gpg("--armor", "--detach-sign", "--output", "-", path)
+	}
+}
+
+func TestCollectionRulesCache_Get(t *testing.T) {
+	keyring, sign := newSigningKey(t)
+	cachedData, remoteData := []byte(`{"version": "1"}`), []byte(`{"version": "2"}`)
+	unreachable := api.NewError(api.ErrServiceUnreachable, nil, nil, "Collection rules could not be downloaded.")
+
+	tests := []struct {
+		Name string
+		// Cached is the ETag of the cached rules; they are not cached when empty.
+		Cached    string
+		Remote    *rules.Rules
+		RemoteErr api.IError
+		Offline   bool
+		Expected  []byte
+		Error     error
+	}{
+		{"cache hit", `"v1"`, &rules.Rules{ETag: `"v1"`, NotModified: true}, nil, false, cachedData, nil},
+		{"etag mismatch", `"v1"`, &rules.Rules{ETag: `"v2"`, Data: remoteData, Signature: sign(remoteData)}, nil, false, remoteData, nil},
+		{"empty cache", "", &rules.Rules{ETag: `"v2"`, Data: remoteData, Signature: sign(remoteData)}, nil, false, remoteData, nil},
+		{"bad signature", `"v1"`, &rules.Rules{ETag: `"v2"`, Data: remoteData, Signature: sign(cachedData)}, nil, false, cachedData, nil},
+		{"bad signature, empty cache", "", &rules.Rules{ETag: `"v2"`, Data: remoteData, Signature: sign(cachedData)}, nil, false, nil, internal.ErrSignature},
+		{"unreachable", `"v1"`, nil, unreachable, false, cachedData, nil},
+		{"unreachable, empty cache", "", nil, unreachable, false, nil, api.ErrServiceUnreachable},
+		{"offline", `"v1"`, nil, nil, true, cachedData, nil},
+		{"offline, empty cache", "", nil, nil, true, nil, internal.ErrNoCache},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			cache := &collectionRulesCache{
+				Path:    filepath.Join(t.TempDir(), rules.RulesFile),
+				Keyring: keyring,
+				Download: func(etag, _ string) (*rules.Rules, api.IError) {
+					if test.Offline {
+						t.Errorf("expected no download when offline")
+					}
+					if etag != test.Cached {
+						t.Errorf("expected ETag '%s', got '%s'", test.Cached, etag)
+					}
+					return test.Remote, test.RemoteErr
+				},
+			}
+			if test.Cached != "" {
+				cached := internal.CachedFile{Path: cache.Path, Data: cachedData, ETag: test.Cached}
+				if err := cached.Write(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			path, err := cache.Get(&Input{Format: internal.JSON}, test.Offline)
+			if test.Error != nil {
+				if err == nil || !err.Is(test.Error) {
+					t.Fatalf("expected '%v', got '%v'", test.Error, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected 'nil', got '%v'", err)
+			}
+			if path != cache.Path {
+				t.Errorf("expected '%s', got '%s'", cache.Path, path)
+			}
+			cached, _ := internal.ReadCachedFile(path)
+			if string(cached.Data) != string(test.Expected) {
+				t.Errorf("expected '%s', got '%s'", test.Expected, cached.Data)
+			}
+		})
+	}
+}
+
+func TestWithCollectionRules(t *testing.T) {
+	original := internal.CollectionRulesCachePath
+	internal.CollectionRulesCachePath = filepath.Join(t.TempDir(), rules.RulesFile)
+	t.Cleanup(func() { internal.CollectionRulesCachePath = original })
+	cached := internal.CachedFile{Path: internal.CollectionRulesCachePath, Data: []byte(`{"version": "1"}`), ETag: `"v1"`}
+	if err := cached.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	declaring := &modules.Module{
+		Name: "declaring",
+		Exec: []string{"/bin/false"},
+		Commands: []modules.ModuleCommand{
+			{Name: []string{"declaring", "collect"}, Flags: []modules.ModuleFlag{{Name: modules.CollectionRulesFlag, Type: 's'}}},
+		},
+		ArchiveCommandName: []string{"declaring", "collect"},
+	}
+	undeclaring := &modules.Module{
+		Name:               "undeclaring",
+		Exec:               []string{"/bin/false"},
+		Commands:           []modules.ModuleCommand{{Name: []string{"undeclaring", "collect"}}},
+		ArchiveCommandName: []string{"undeclaring", "collect"},
+	}
+
+	tests := []struct {
+		Name     string
+		Module   *modules.Module
+		Options  []string
+		Expected []string
+	}{
+		{"declared", declaring, []string{"--x"}, []string{"--x", "--collection-rules=" + internal.CollectionRulesCachePath}},
+		{"passed by user", declaring, []string{"--collection-rules=/rules.json"}, []string{"--collection-rules=/rules.json"}},
+		{"not declared", undeclaring, []string{"--x"}, []string{"--x"}},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			options := withCollectionRules(&Input{Format: internal.JSON}, test.Module, test.Options, true)
+			if strings.Join(options, " ") != strings.Join(test.Expected, " ") {
+				t.Errorf("expected '%v', got '%v'", test.Expected, options)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// iniValue is a value of an INI option along with the line it was defined on.
+type iniValue struct {
+	Value string
+	Line  int
+}
+
+// iniDocument is an INI file parsed into sections of options.
+type iniDocument struct {
+	Sections map[string]map[string]iniValue
+}
+
+// parseINI parses the INI file the way Python's configparser reads insights-client.conf.
+//
+// Option names are case-insensitive, both `=` and `:` separate names from values,
+// and indented lines continue the value of the previous option.
+// Lines that cannot be parsed are skipped and reported.
+func parseINI(path string, data []byte) (*iniDocument, []ConfigurationIssue) {
+	document := &iniDocument{Sections: make(map[string]map[string]iniValue)}
+	var issues []ConfigurationIssue
+
+	section := ""
+	option := ""
+	number := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		number++
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if option != "" && (raw[0] == ' ' || raw[0] == '\t') {
+			value := document.Sections[section][option]
+			value.Value += "\n" + line
+			document.Sections[section][option] = value
+			continue
+		}
+		option = ""
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if _, ok := document.Sections[section]; !ok {
+				document.Sections[section] = make(map[string]iniValue)
+			}
+			continue
+		}
+
+		delimiter := strings.IndexAny(line, "=:")
+		if delimiter < 1 {
+			issues = append(issues, ConfigurationIssue{path, number, "", SeverityError, "expected 'key=value'"})
+			continue
+		}
+		if section == "" {
+			issues = append(issues, ConfigurationIssue{path, number, "", SeverityError, "option is not in any section"})
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:delimiter]))
+		if previous, ok := document.Sections[section][key]; ok {
+			issues = append(issues, ConfigurationIssue{
+				path, number, key, SeverityWarning,
+				fmt.Sprintf("'%s' overrides its value from line %d", key, previous.Line),
+			})
+		}
+		document.Sections[section][key] = iniValue{Value: strings.TrimSpace(line[delimiter+1:]), Line: number}
+		option = key
+	}
+	return document, issues
+}
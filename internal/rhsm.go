@@ -1,9 +1,17 @@
 package internal
 
 import (
+	"cmp"
 	"crypto/x509"
 	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 )
 
 // ReadRHSMIdentity reads the CommonName from x.509 certificate.
@@ -27,3 +35,140 @@ func ReadRHSMIdentity(filename string) (string, IError) {
 
 	return cert.Subject.CommonName, nil
 }
+
+// RHSMConfiguration contains values of the subscription-manager configuration the client reuses.
+type RHSMConfiguration struct {
+	// Hostname, Port and Prefix point to the entitlement server, e.g. Satellite.
+	Hostname string
+	Port     string
+	Prefix   string
+	// ConsumerCertDir contains the identity certificate and key of the host.
+	ConsumerCertDir string
+	// CACertDir contains certificates of the authorities trusted by subscription-manager.
+	CACertDir string
+	// RepoCACert is a certificate of the authority of the content server.
+	RepoCACert    string
+	ProxyScheme   string
+	ProxyHostname string
+	ProxyPort     string
+	ProxyUser     string
+	ProxyPassword string
+
+	// lines map option names to lines they were set on.
+	lines map[string]int
+}
+
+// Defaults of subscription-manager, used when rhsm.conf does not set the option.
+const (
+	rhsmDefaultConsumerCertDir = "/etc/pki/consumer"
+	rhsmDefaultCACertDir       = "/etc/rhsm/ca/"
+)
+
+var rhsmInterpolationRegex = regexp.MustCompile(`%\((\w+)\)s`)
+
+// ReadRHSMConfiguration parses the rhsm.conf file.
+//
+// Options referencing other options of the same section (`%(ca_cert_dir)s`) are expanded.
+func ReadRHSMConfiguration(path string) (*RHSMConfiguration, IError) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, NewError(nil, err, fmt.Sprintf("Could not read '%s'.", path))
+	}
+	document, issues := parseINI(path, data)
+	for _, issue := range issues {
+		slog.Debug("rhsm configuration issue", slog.String("issue", issue.String()))
+	}
+
+	rhsm := &RHSMConfiguration{lines: make(map[string]int)}
+	get := func(section, key string) string {
+		value, ok := document.Sections[section][key]
+		if !ok {
+			return ""
+		}
+		rhsm.lines[key] = value.Line
+		expanded := rhsmInterpolationRegex.ReplaceAllStringFunc(value.Value, func(reference string) string {
+			name := rhsmInterpolationRegex.FindStringSubmatch(reference)[1]
+			if referenced, ok := document.Sections[section][name]; ok {
+				return referenced.Value
+			}
+			return reference
+		})
+		return strings.ReplaceAll(expanded, "%%", "%")
+	}
+
+	rhsm.Hostname = get("server", "hostname")
+	rhsm.Port = get("server", "port")
+	rhsm.Prefix = get("server", "prefix")
+	rhsm.ProxyScheme = get("server", "proxy_scheme")
+	rhsm.ProxyHostname = get("server", "proxy_hostname")
+	rhsm.ProxyPort = get("server", "proxy_port")
+	rhsm.ProxyUser = get("server", "proxy_user")
+	rhsm.ProxyPassword = get("server", "proxy_password")
+	rhsm.ConsumerCertDir = get("rhsm", "consumercertdir")
+	rhsm.CACertDir = get("rhsm", "ca_cert_dir")
+	rhsm.RepoCACert = get("rhsm", "repo_ca_cert")
+	return rhsm, nil
+}
+
+// IsSatellite reports whether the host is registered to Red Hat Satellite or Capsule
+// instead of Red Hat Subscription Management.
+func (r *RHSMConfiguration) IsSatellite() bool {
+	return r.Hostname != "" && !strings.HasSuffix(r.Hostname, ".redhat.com")
+}
+
+// IdentityCertificate returns the path to the identity certificate of the host.
+func (r *RHSMConfiguration) IdentityCertificate() string {
+	return filepath.Join(cmp.Or(r.ConsumerCertDir, rhsmDefaultConsumerCertDir), "cert.pem")
+}
+
+// IdentityKey returns the path to the private key of the host.
+func (r *RHSMConfiguration) IdentityKey() string {
+	return filepath.Join(cmp.Or(r.ConsumerCertDir, rhsmDefaultConsumerCertDir), "key.pem")
+}
+
+// Proxy returns the URL of the proxy, or an empty string if no proxy is configured.
+func (r *RHSMConfiguration) Proxy() string {
+	if r.ProxyHostname == "" {
+		return ""
+	}
+	address := &url.URL{Scheme: cmp.Or(r.ProxyScheme, "http"), Host: r.ProxyHostname}
+	if r.ProxyPort != "" {
+		address.Host = net.JoinHostPort(r.ProxyHostname, r.ProxyPort)
+	}
+	if r.ProxyUser != "" {
+		address.User = url.UserPassword(r.ProxyUser, r.ProxyPassword)
+	}
+	return address.String()
+}
+
+// layer converts the values into configuration keys of the client.
+//
+// Options that are not set in rhsm.conf are not included.
+func (r *RHSMConfiguration) layer(origin string) configurationLayer {
+	layer := configurationLayer{Origin: origin, Data: make(map[string]string), Lines: make(map[string]int)}
+	set := func(key, value, option string) {
+		if value != "" {
+			layer.Data[key] = value
+			layer.Lines[key] = r.lines[option]
+		}
+	}
+
+	if r.ConsumerCertDir != "" {
+		set("identity_certificate", r.IdentityCertificate(), "consumercertdir")
+		set("identity_key", r.IdentityKey(), "consumercertdir")
+	}
+	// Satellite uses its own CA, it is usually set as the CA of the content server.
+	// The certificate is added to the system trust store, so it is safe to use it for Red Hat as well.
+	if r.RepoCACert != "" {
+		set("ca_certificate", r.RepoCACert, "repo_ca_cert")
+	} else {
+		set("ca_certificate", r.CACertDir, "ca_cert_dir")
+	}
+	set("proxy", r.Proxy(), "proxy_hostname")
+	if r.IsSatellite() {
+		set("satellite", "true", "hostname")
+		set("api_host", r.Hostname, "hostname")
+		set("api_port", r.Port, "port")
+	}
+	return layer
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+)
+
+// Secrets are credentials that must not be stored in the configuration file, which is world-readable.
+type Secrets struct {
+	Password          string
+	TokenClientSecret string
+}
+
+// ReadSecrets loads credentials from the `[insights-client]` section of SecretsPath.
+//
+// The file is refused when it is not owned by the user running the client,
+// or when its group or other users have any permissions.
+func ReadSecrets() (*Secrets, IError) {
+	// The checks and the read use the same descriptor, so the file cannot be swapped in between.
+	file, err := os.Open(SecretsPath)
+	if err != nil {
+		return nil, NewError(ErrConfiguration, err, fmt.Sprintf("Could not read secrets file '%s'.", SecretsPath))
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, NewError(ErrConfiguration, err, fmt.Sprintf("Could not read secrets file '%s'.", SecretsPath))
+	}
+	if stat.Mode().Perm()&0o077 != 0 {
+		return nil, NewError(
+			ErrPermissions, fmt.Errorf("secrets file has mode %o", stat.Mode().Perm()),
+			fmt.Sprintf("Secrets file '%s' must not be accessible by other users.", SecretsPath),
+		)
+	}
+	if owner, ok := stat.Sys().(*syscall.Stat_t); ok && int(owner.Uid) != os.Geteuid() {
+		return nil, NewError(
+			ErrPermissions, fmt.Errorf("secrets file is owned by %d", owner.Uid),
+			fmt.Sprintf("Secrets file '%s' must be owned by the user running the client.", SecretsPath),
+		)
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, NewError(ErrConfiguration, err, fmt.Sprintf("Could not read secrets file '%s'.", SecretsPath))
+	}
+	document, issues := parseINI(SecretsPath, data)
+	if len(issues) > 0 {
+		return nil, NewError(ErrConfiguration, fmt.Errorf("%s", issues[0].String()), fmt.Sprintf("Secrets file '%s' is not valid.", SecretsPath))
+	}
+	section := document.Sections[ConfigurationSection]
+	return &Secrets{
+		Password:          section["password"].Value,
+		TokenClientSecret: section["token_client_secret"].Value,
+	}, nil
+}
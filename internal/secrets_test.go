@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadSecrets(t *testing.T) {
+	original := SecretsPath
+	SecretsPath = filepath.Join(t.TempDir(), "secrets.conf")
+	t.Cleanup(func() { SecretsPath = original })
+
+	if err := os.WriteFile(SecretsPath, []byte("[insights-client]\npassword=hunter2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSecrets(); err == nil || !err.Is(ErrPermissions) {
+		t.Errorf("expected '%v', got '%v'", ErrPermissions, err)
+	}
+
+	if err := os.Chmod(SecretsPath, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	secrets, err := ReadSecrets()
+	if err != nil {
+		t.Fatalf("expected 'nil', got '%v'", err)
+	}
+	if secrets.Password != "hunter2" {
+		t.Errorf("expected 'hunter2', got '%s'", secrets.Password)
+	}
+}
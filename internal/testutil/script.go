@@ -0,0 +1,26 @@
+// Package testutil contains fixtures shared by tests of multiple packages.
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// WriteScript creates an executable shell script at the path, along with its parent directories.
+//
+// The body is prefixed with the shebang. The path is returned, so the script can be created in place.
+func WriteScript(t testing.TB, path, body string) string {
+	t.Helper()
+	if !strings.HasSuffix(body, "\n") {
+		body += "\n"
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
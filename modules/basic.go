@@ -0,0 +1,189 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/m-horky/insights-client-next/internal"
+)
+
+// Spec describes a single piece of data collected by a built-in module.
+//
+// Exactly one of File, Glob and Command is set.
+type Spec struct {
+	Name    string
+	File    string
+	Glob    string
+	Command []string
+}
+
+// BasicSpecs are collected by the basic module.
+//
+// They are sufficient for Inventory to build the system profile of the host.
+var BasicSpecs = []Spec{
+	{Name: "machine_id", File: "/etc/insights-client/machine-id"},
+	{Name: "etc_machine_id", File: "/etc/machine-id"},
+	{Name: "os_release", File: "/etc/os-release"},
+	{Name: "redhat_release", File: "/etc/redhat-release"},
+	{Name: "hostname", File: "/etc/hostname"},
+	{Name: "cpuinfo", File: "/proc/cpuinfo"},
+	{Name: "meminfo", File: "/proc/meminfo"},
+	{Name: "cmdline", File: "/proc/cmdline"},
+	{Name: "selinux_config", File: "/etc/selinux/config"},
+	{Name: "yum_repos_d", Glob: "/etc/yum.repos.d/*.repo"},
+	{Name: "uname", Command: []string{"/usr/bin/uname", "-a"}},
+	{Name: "hostname_default", Command: []string{"/usr/bin/hostname", "-f"}},
+	{Name: "uptime", Command: []string{"/usr/bin/uptime"}},
+	{Name: "installed_rpms", Command: []string{"/usr/bin/rpm", "-qa", "--qf", "%{NAME}-%{VERSION}-%{RELEASE}.%{ARCH}\\n"}},
+	{Name: "ip_addr", Command: []string{"/usr/sbin/ip", "addr"}},
+	{Name: "dmidecode", Command: []string{"/usr/sbin/dmidecode"}},
+}
+
+// BasicContentType is the content type of archives collected by the basic module.
+//
+// The archive only contains `data/` with the specs; it lacks the metadata of the Core,
+// so it must not be processed as an Advisor collection.
+const BasicContentType = "application/vnd.redhat.basic.collection"
+
+// GetBasicModule returns a module collecting BasicSpecs without any external dependency.
+//
+// It can be used on hosts where Python or the Core are not available.
+func GetBasicModule() *Module {
+	module := &Module{
+		Name:    "basic",
+		Version: internal.Version,
+		Commands: []ModuleCommand{
+			{Name: []string{"basic", "collect"}},
+			{Name: []string{"basic", "list-specs"}},
+		},
+		ArchiveCommandName: []string{"basic", "collect"},
+		ArchiveContentType: BasicContentType,
+	}
+	module.builtin = func(ctx context.Context, command, args []string, options *RunOptions) IError {
+		return runBasicModule(ctx, module, command, args, options)
+	}
+	return module
+}
+
+// runBasicModule implements commands of the basic module.
+func runBasicModule(ctx context.Context, m *Module, command, args []string, options *RunOptions) IError {
+	switch strings.Join(command, " ") {
+	case "basic list-specs":
+		stdout := options.Stdout
+		if stdout == nil {
+			stdout = io.Discard
+		}
+		for _, spec := range BasicSpecs {
+			source := spec.File + spec.Glob + strings.Join(spec.Command, " ")
+			_, _ = fmt.Fprintf(stdout, "%s\t%s\n", spec.Name, source)
+		}
+		return nil
+	case "basic collect":
+		directory := ""
+		for _, arg := range args {
+			if value, found := strings.CutPrefix(arg, "--archive="); found {
+				directory = value
+				continue
+			}
+			return NewError(ErrRun, nil, fmt.Sprintf("Unexpected argument '%s'.", arg))
+		}
+		if directory == "" {
+			return NewError(ErrRun, nil, "Archive directory was not specified.")
+		}
+		// Issues of the denylist are logged by ReadDenylist and reported by the client.
+		denylist, _, err := internal.ReadDenylist()
+		if err != nil {
+			return err
+		}
+		return collectSpecs(ctx, directory, BasicSpecs, denylist, m.Environment(options.Environment), options.OnProgress)
+	}
+	return NewError(ErrNoModule, nil, fmt.Sprintf("Module '%s' does not implement command '%s'.", m.Name, strings.Join(command, " ")))
+}
+
+// collectSpecs saves the data described by specs into the archive directory.
+//
+// Files are stored under `data/` with their original path, command output is stored
+// under `data/insights_commands/`. Specs which are denied or not available are skipped.
+func collectSpecs(ctx context.Context, directory string, specs []Spec, denylist *internal.Denylist, env []string, onProgress func(ProgressEvent)) IError {
+	for i, spec := range specs {
+		if ctx.Err() != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return NewError(ErrTimeout, ctx.Err(), "Module command did not finish in time.")
+			}
+			return NewError(ErrCanceled, ctx.Err(), "Module command was canceled.")
+		}
+		if onProgress != nil {
+			percent := float64(i) * 100 / float64(len(specs))
+			onProgress(ProgressEvent{Type: "progress", Message: fmt.Sprintf("Collecting %s.", spec.Name), Percent: &percent})
+		}
+
+		var err error
+		switch {
+		case spec.File != "":
+			err = collectFile(directory, spec.File, denylist)
+		case spec.Glob != "":
+			var paths []string
+			paths, err = filepath.Glob(spec.Glob)
+			for _, path := range paths {
+				if err = collectFile(directory, path, denylist); err != nil {
+					break
+				}
+			}
+		case len(spec.Command) > 0:
+			err = collectCommand(ctx, directory, spec.Command, denylist, env)
+		}
+		if err != nil {
+			return NewError(ErrRun, err, fmt.Sprintf("Could not collect '%s'.", spec.Name))
+		}
+	}
+	return nil
+}
+
+// collectFile copies the file into the archive directory.
+func collectFile(directory, path string, denylist *internal.Denylist) error {
+	if !denylist.AllowsFile(path) {
+		slog.Debug("skipping denied file", slog.String("path", path))
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		slog.Debug("skipping unavailable file", slog.String("path", path), slog.String("error", err.Error()))
+		return nil
+	}
+	return writeSpecData(filepath.Join(directory, "data", path), denylist.Filter(data))
+}
+
+// collectCommand saves the output of the command into the archive directory.
+func collectCommand(ctx context.Context, directory string, command []string, denylist *internal.Denylist, env []string) error {
+	if !denylist.AllowsCommand(command) {
+		slog.Debug("skipping denied command", slog.String("command", strings.Join(command, " ")))
+		return nil
+	}
+	if _, err := os.Stat(command[0]); err != nil {
+		slog.Debug("skipping unavailable command", slog.String("command", command[0]))
+		return nil
+	}
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		slog.Debug("skipping failed command", slog.String("command", strings.Join(command, " ")), slog.String("error", err.Error()))
+		return nil
+	}
+	return writeSpecData(filepath.Join(directory, "data", "insights_commands", internal.CommandFileName(command)), denylist.Filter(output))
+}
+
+func writeSpecData(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o640)
+}
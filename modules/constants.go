@@ -1,4 +1,21 @@
 package modules
 
-// InsightsCorePath is a filesystem file with the Core.
+// InsightsCorePath is a filesystem file with the Core shipped in the RPM package.
 var InsightsCorePath = "/etc/insights-client/rpm.egg"
+
+// NewestEggPath is the newest Core downloaded from the API. It has not been proven to work yet.
+var NewestEggPath = "/var/lib/insights/newest.egg"
+
+// RejectedEggETagPath stores the ETag of the newest Core that failed to collect data.
+var RejectedEggETagPath = "/var/lib/insights/newest.egg.rejected"
+
+// LastStableEggPath is the Core which has successfully collected data.
+var LastStableEggPath = "/var/lib/insights/last_stable.egg"
+
+// ModuleDirectories contain module manifests.
+//
+// Manifests in later directories override modules of the same name defined earlier.
+var ModuleDirectories = []string{"/usr/lib/insights-client/modules.d/", "/etc/insights-client/modules.d/"}
+
+// ModuleDirectoryOwner is the user ID ModuleDirectories and the manifests have to be owned by.
+var ModuleDirectoryOwner = 0
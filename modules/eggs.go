@@ -0,0 +1,116 @@
+package modules
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/m-horky/insights-client-next/internal"
+)
+
+// Eggs locate the Core eggs.
+//
+// To locate the eggs of the system, use SystemEggs.
+type Eggs struct {
+	// NewestPath is the newest Core downloaded from the API, see NewestEggPath.
+	NewestPath string
+	// RejectedETagPath stores the ETag of the newest Core that failed, see RejectedEggETagPath.
+	RejectedETagPath string
+	// LastStablePath is the Core which has successfully collected data, see LastStableEggPath.
+	LastStablePath string
+	// RPMPath is the Core shipped in the RPM package, see InsightsCorePath.
+	RPMPath string
+}
+
+// SystemEggs returns the eggs at the system paths, e.g. NewestEggPath.
+func SystemEggs() *Eggs {
+	return &Eggs{
+		NewestPath:       NewestEggPath,
+		RejectedETagPath: RejectedEggETagPath,
+		LastStablePath:   LastStableEggPath,
+		RPMPath:          InsightsCorePath,
+	}
+}
+
+// Candidates returns the Core eggs modules may run with, the preferred one first.
+//
+// The EGG environment variable overrides all other eggs. Downloaded eggs are only
+// used when `auto_update` is enabled. The RPM egg is always included as the last resort.
+func (e *Eggs) Candidates() []string {
+	if egg := os.Getenv("EGG"); egg != "" {
+		return []string{egg}
+	}
+
+	var eggs []string
+	if internal.GetConfiguration().AutoUpdate {
+		for _, egg := range []string{e.NewestPath, e.LastStablePath} {
+			if _, err := os.Stat(egg); err == nil {
+				eggs = append(eggs, egg)
+			}
+		}
+	}
+	return append(eggs, e.RPMPath)
+}
+
+// UsesCore reports whether the module is implemented by the Core.
+func (m *Module) UsesCore() bool {
+	return m.core
+}
+
+// coreEggs returns the eggs the Core module runs with.
+func (m *Module) coreEggs() *Eggs {
+	if m.eggs != nil {
+		return m.eggs
+	}
+	return SystemEggs()
+}
+
+// corePythonPath returns PYTHONPATH which loads the Core from the egg.
+func corePythonPath(egg string) string {
+	if inherited := os.Getenv("PYTHONPATH"); inherited != "" {
+		return egg + ":" + inherited
+	}
+	return egg
+}
+
+// promote marks the newest egg as stable, along with its signature.
+func (e *Eggs) promote() {
+	for _, suffix := range []string{"", ".asc"} {
+		data, err := os.ReadFile(e.NewestPath + suffix)
+		if err != nil {
+			slog.Warn("could not promote egg", slog.String("error", err.Error()))
+			return
+		}
+		if err = os.WriteFile(e.LastStablePath+suffix, data, 0o644); err != nil {
+			slog.Warn("could not promote egg", slog.String("error", err.Error()))
+			return
+		}
+	}
+	slog.Info("egg promoted", slog.String("path", e.LastStablePath))
+}
+
+// discard removes the newest egg, so it is not used again.
+//
+// Its ETag is kept as rejected, so the same egg is not downloaded again.
+func (e *Eggs) discard() {
+	if cached, err := internal.ReadCachedFile(e.NewestPath); err == nil && cached.ETag != "" {
+		if err := os.WriteFile(e.RejectedETagPath, []byte(cached.ETag), 0o644); err != nil {
+			slog.Warn("could not store ETag of discarded egg", slog.String("error", err.Error()))
+		}
+	}
+	for _, suffix := range []string{"", ".asc", ".meta"} {
+		if err := os.Remove(e.NewestPath + suffix); err != nil && !os.IsNotExist(err) {
+			slog.Warn("could not discard egg", slog.String("error", err.Error()))
+		}
+	}
+	slog.Warn("egg discarded", slog.String("path", e.NewestPath))
+}
+
+// RejectedETag returns the ETag of the last discarded egg, or an empty string.
+func (e *Eggs) RejectedETag() string {
+	data, err := os.ReadFile(e.RejectedETagPath)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,112 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/m-horky/insights-client-next/internal/testutil"
+)
+
+func TestModule_Collect_eggFallback(t *testing.T) {
+	t.Setenv("EGG", "")
+	t.Setenv("PYTHONPATH", "")
+	directory := t.TempDir()
+	eggs := &Eggs{
+		NewestPath:       filepath.Join(directory, "newest.egg"),
+		RejectedETagPath: filepath.Join(directory, "newest.egg.rejected"),
+		LastStablePath:   filepath.Join(directory, "last_stable.egg"),
+		RPMPath:          filepath.Join(directory, "rpm.egg"),
+	}
+	for _, egg := range []string{eggs.NewestPath, eggs.LastStablePath, eggs.RPMPath} {
+		if err := os.WriteFile(egg, []byte(filepath.Base(egg)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The module only works with the egg whose name is stored in the 'working' file.
+	working := filepath.Join(directory, "working")
+	script := testutil.WriteScript(t, filepath.Join(directory, "module"),
+		`[ "$(cat "$PYTHONPATH")" = "$(cat `+working+`)" ] && touch "${3#--archive=}/collected"`,
+	)
+	module := &Module{
+		Name:               "scanner",
+		Exec:               []string{script},
+		ArchiveCommandName: []string{"scanner", "collect"},
+		core:               true,
+		eggs:               eggs,
+	}
+
+	// The newest egg fails and is discarded, the stable one is used instead.
+	if err := os.WriteFile(eggs.NewestPath+".meta", []byte(`{"etag": "\"rejected\""}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(working, []byte("last_stable.egg"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	archive := t.TempDir()
+	if err := module.Collect(archive, nil, nil); err != nil {
+		t.Fatalf("expected 'nil', got '%v'", err)
+	}
+	if _, err := os.Stat(eggs.NewestPath); err == nil {
+		t.Errorf("expected newest egg to be discarded")
+	}
+	if etag := eggs.RejectedETag(); etag != `"rejected"` {
+		t.Errorf("expected ETag of the discarded egg to be kept, got '%s'", etag)
+	}
+
+	// The newest egg works and is promoted.
+	if err := os.WriteFile(eggs.NewestPath, []byte("newest.egg"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(eggs.NewestPath+".asc", []byte("signature"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(working, []byte("newest.egg"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := module.Collect(t.TempDir(), nil, nil); err != nil {
+		t.Fatalf("expected 'nil', got '%v'", err)
+	}
+	if data, _ := os.ReadFile(eggs.LastStablePath); string(data) != "newest.egg" {
+		t.Errorf("expected newest egg to be promoted, got '%s'", data)
+	}
+}
+
+func TestModule_Collect_eggTimeout(t *testing.T) {
+	t.Setenv("EGG", "")
+	directory := t.TempDir()
+	eggs := &Eggs{
+		NewestPath:       filepath.Join(directory, "newest.egg"),
+		RejectedETagPath: filepath.Join(directory, "newest.egg.rejected"),
+		LastStablePath:   filepath.Join(directory, "last_stable.egg"),
+		RPMPath:          filepath.Join(directory, "rpm.egg"),
+	}
+	for _, egg := range []string{eggs.NewestPath, eggs.LastStablePath, eggs.RPMPath} {
+		if err := os.WriteFile(egg, []byte(filepath.Base(egg)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	calls := filepath.Join(directory, "calls")
+	script := testutil.WriteScript(t, filepath.Join(directory, "module"), "echo run >> "+calls+"\nexec sleep 30")
+	module := &Module{
+		Name:               "scanner",
+		Exec:               []string{script},
+		ArchiveCommandName: []string{"scanner", "collect"},
+		core:               true,
+		eggs:               eggs,
+	}
+
+	// A slow host is not a reason to reject the egg or to retry with another one.
+	err := module.Collect(t.TempDir(), nil, &RunOptions{Timeout: 100 * time.Millisecond, KillTimeout: 100 * time.Millisecond})
+	if err == nil || !err.Is(ErrTimeout) {
+		t.Fatalf("expected '%v', got '%v'", ErrTimeout, err)
+	}
+	if data, _ := os.ReadFile(calls); string(data) != "run\n" {
+		t.Errorf("expected the collection to run once, got '%s'", data)
+	}
+	if _, err := os.Stat(eggs.NewestPath); err != nil {
+		t.Errorf("expected newest egg to be kept, got '%v'", err)
+	}
+}
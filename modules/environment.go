@@ -0,0 +1,50 @@
+package modules
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// InheritedEnvironment lists variables that are passed from the client to all modules.
+//
+// Modules can inherit more variables by declaring them in Module.InheritEnv.
+var InheritedEnvironment = []string{
+	"PATH", "LANG", "TZ",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy",
+}
+
+// defaultPath is used when the client runs without PATH.
+const defaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// Environment builds the environment the module runs in.
+//
+// Inherited variables are overridden by variables declared by the module,
+// which are overridden by `overrides`.
+func (m *Module) Environment(overrides map[string]string) []string {
+	variables := map[string]string{"PATH": defaultPath}
+
+	for _, name := range append(append([]string{}, InheritedEnvironment...), m.InheritEnv...) {
+		if value, ok := os.LookupEnv(name); ok {
+			variables[name] = value
+		}
+	}
+	for _, variable := range m.Env {
+		if name, value, found := strings.Cut(variable, "="); found {
+			variables[name] = value
+		}
+	}
+	if m.core {
+		variables["PYTHONPATH"] = corePythonPath(m.coreEggs().Candidates()[0])
+	}
+	for name, value := range overrides {
+		variables[name] = value
+	}
+
+	env := make([]string, 0, len(variables))
+	for name, value := range variables {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
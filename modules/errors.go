@@ -6,8 +6,13 @@ import (
 )
 
 var (
-	ErrNoModule = errors.New("no such module")
-	ErrRun      = errors.New("could not run module")
+	ErrNoModule      = errors.New("no such module")
+	ErrRun           = errors.New("could not run module")
+	ErrManifest      = errors.New("bad module manifest")
+	ErrProtocol      = errors.New("module protocol violation")
+	ErrTimeout       = errors.New("module timed out")
+	ErrCanceled      = errors.New("module was canceled")
+	ErrResourceLimit = errors.New("module exceeded resource limits")
 )
 
 type IError interface {
@@ -2,52 +2,59 @@ package modules
 
 import (
 	"bytes"
-	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 )
 
+// CollectionRulesFlag passes verified collection rules to modules declaring it.
+//
+// Modules that do not declare the flag fetch the rules on their own.
+const CollectionRulesFlag = "collection-rules"
+
+// The Core does not implement the module protocol, its modules are not asked to describe themselves.
+// Their version is read by getInsightsCoreVersion.
+
 func GetAdvisorModule() *Module {
 	return &Module{
-		Name:    "advisor",
-		Version: getInsightsCoreVersion(),
-		Env:     getInsightsCoreEnv(),
-		Exec:    []string{"python3", "-m", "insights.client.phase.v2"},
+		Name: "advisor",
+		Env:  getInsightsCoreEnv(),
+		Exec: []string{"python3", "-m", "insights.client.phase.v2"},
 		Commands: []ModuleCommand{
-			{Name: []string{"advisor", "collect"}},
-			{Name: []string{"advisor", "check-results"}},
-			{Name: []string{"advisor", "show-results"}},
-			{Name: []string{"advisor", "list-specs"}},
-			{Name: []string{"advisor", "diagnosis"}},
+			{Name: []string{"advisor", "collect"}, Flags: []ModuleFlag{
+				{Name: CollectionRulesFlag, Type: 's', Help: "path to verified collection rules"},
+			}},
 			{Name: []string{"advisor", "manifest"}},
 			{Name: []string{"advisor", "build-packagecache"}},
-			{Name: []string{"advisor", "validate"}},
 		},
 		ArchiveCommandName: []string{"advisor", "collect"},
 		ArchiveContentType: "application/vnd.redhat.advisor.collection",
+		versionFallback:    getInsightsCoreVersion,
+		core:               true,
 	}
 }
 
 func GetComplianceModule() *Module {
 	return &Module{
-		Name:    "compliance",
-		Version: getInsightsCoreVersion(),
-		Env:     getInsightsCoreEnv(),
-		Exec:    []string{"python3", "-m", "insights.client.phase.v2"},
+		Name: "compliance",
+		Env:  getInsightsCoreEnv(),
+		Exec: []string{"python3", "-m", "insights.client.phase.v2"},
 		Commands: []ModuleCommand{
 			{Name: []string{"compliance", "collect"}},
 		},
 		ArchiveCommandName: []string{"compliance", "collect"},
-		ArchiveContentType: "application/vnd.redhat.compliance.something",
+		ArchiveContentType: "application/vnd.redhat.compliance.collection",
+		versionFallback:    getInsightsCoreVersion,
+		core:               true,
 	}
 }
 
 func GetMalwareModule() *Module {
 	return &Module{
 		Name:    "malware",
-		Version: getInsightsCoreVersion(),
+		Aliases: []string{"malware-detection"},
 		Env:     getInsightsCoreEnv(),
 		Exec:    []string{"python3", "-m", "insights.client.phase.v2"},
 		Commands: []ModuleCommand{
@@ -55,47 +62,30 @@ func GetMalwareModule() *Module {
 		},
 		ArchiveCommandName: []string{"malware", "collect"},
 		ArchiveContentType: "application/vnd.redhat.malware-detection.results",
+		versionFallback:    getInsightsCoreVersion,
+		core:               true,
 	}
 }
 
 // getInsightsCoreEnv sets up the environment for the Python subshell.
 //
 // It sets LC_ALL=C.UTF-8 to ensure we don't need to deal with non-supported locales.
-// It sets PYTHONPATH to include the path to the Core Egg from its default location of
-// InsightsCorePath, or to file defined by the EGG environment variable.
+// PYTHONPATH pointing to the Core egg is added by Module.Environment, see Eggs.Candidates.
 func getInsightsCoreEnv() []string {
-	env := []string{"LC_ALL=C.UTF-8"}
-	pythonPath := os.Getenv("PYTHONPATH")
-	if egg := os.Getenv("EGG"); egg != "" {
-		env = append(env, fmt.Sprintf("PYTHONPATH=%s", egg+":"+pythonPath))
-	} else {
-		env = append(env, fmt.Sprintf("PYTHONPATH=%s", InsightsCorePath+":"+pythonPath))
-	}
-	return env
+	return []string{"LC_ALL=C.UTF-8"}
 }
 
-var insightsCoreVersionIsCached = false
-var insightsCoreVersion = ""
-
 // getInsightsCoreVersion runs the Core to figure out what version it has.
 //
 // Since the Core does not have its metadata in a file, we need to read this dynamically.
-func getInsightsCoreVersion() string {
-	if insightsCoreVersionIsCached {
-		return insightsCoreVersion
-	}
-
+// The version is only read once, it is safe for concurrent use.
+var getInsightsCoreVersion = sync.OnceValue(func() string {
 	if os.Geteuid() != 0 {
-		insightsCoreVersion = "??? (not root)"
-		insightsCoreVersionIsCached = true
-		return insightsCoreVersion
+		return "??? (not root)"
 	}
 
 	cmd := exec.Command("python3", "-c", "from insights.client import InsightsClient; print(InsightsClient(None, False).version())")
-	for _, variable := range os.Environ() {
-		cmd.Env = append(cmd.Env, variable)
-	}
-	cmd.Env = append(cmd.Env, fmt.Sprintf(`PYTHONPATH=/etc/insights-client/rpm.egg:%s"`, os.Getenv(`PYTHONPATH`)))
+	cmd.Env = (&Module{Env: getInsightsCoreEnv(), core: true}).Environment(nil)
 
 	var stdoutBuffer, stderrBuffer bytes.Buffer
 	cmd.Stdout = &stdoutBuffer
@@ -110,12 +100,8 @@ func getInsightsCoreVersion() string {
 			slog.Any("stdout", stdoutBuffer.String()),
 			slog.Any("stderr", stderrBuffer.String()),
 		)
-		insightsCoreVersion = "??? (parsing error)"
-		insightsCoreVersionIsCached = true
-		return insightsCoreVersion
+		return "??? (parsing error)"
 	}
 
-	insightsCoreVersion = strings.TrimSpace(stdoutBuffer.String())
-	insightsCoreVersionIsCached = true
-	return insightsCoreVersion
-}
+	return strings.TrimSpace(stdoutBuffer.String())
+})
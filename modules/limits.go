@@ -0,0 +1,45 @@
+package modules
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// ResourceLimits are applied to modules running inside a transient systemd scope.
+//
+// Values use the systemd.resource-control(5) format; empty values are not applied.
+type ResourceLimits struct {
+	// Slice places the scope into a slice, e.g. `insights-client.slice`.
+	Slice      string
+	CPUQuota   string
+	MemoryHigh string
+	MemoryMax  string
+	TasksMax   string
+	IOWeight   string
+}
+
+// wrap prefixes the command so it runs in a transient systemd scope.
+func (l *ResourceLimits) wrap(name string, argv []string) []string {
+	wrapped := []string{
+		"systemd-run", "--scope", "--quiet", "--collect",
+		fmt.Sprintf("--unit=insights-client-%s-%d-%d", name, os.Getpid(), time.Now().UnixNano()),
+	}
+	if l.Slice != "" {
+		wrapped = append(wrapped, fmt.Sprintf("--slice=%s", l.Slice))
+	}
+	properties := []struct{ key, value string }{
+		{"CPUQuota", l.CPUQuota},
+		{"MemoryHigh", l.MemoryHigh},
+		{"MemoryMax", l.MemoryMax},
+		{"TasksMax", l.TasksMax},
+		{"IOWeight", l.IOWeight},
+	}
+	for _, property := range properties {
+		if property.value != "" {
+			wrapped = append(wrapped, "--property", fmt.Sprintf("%s=%s", property.key, property.value))
+		}
+	}
+	wrapped = append(wrapped, "--")
+	return append(wrapped, argv...)
+}
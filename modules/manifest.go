@@ -0,0 +1,244 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/m-horky/insights-client-next/internal"
+)
+
+// Manifest is a declarative module definition.
+//
+// Manifests are YAML or JSON files stored in ModuleDirectories.
+type Manifest struct {
+	Name    string   `yaml:"name"`
+	Aliases []string `yaml:"aliases"`
+	Version string   `yaml:"version"`
+	Exec    []string `yaml:"exec"`
+	Env     []string `yaml:"env"`
+	// InheritEnv lists variables passed from the client, see InheritedEnvironment.
+	InheritEnv []string `yaml:"inherit_env"`
+	// Privileges are declared by modules that do not need to run as root.
+	Privileges *Privileges `yaml:"privileges"`
+	// Protocol is the module protocol version the module supports, see ProtocolVersion.
+	Protocol int `yaml:"protocol"`
+
+	Commands []ManifestCommand `yaml:"commands"`
+
+	ArchiveCommand       []string `yaml:"archive_command"`
+	ContentType          string   `yaml:"content_type"`
+	MinimumClientVersion string   `yaml:"minimum_client_version"`
+}
+
+// ManifestCommand is contained in Manifest.
+type ManifestCommand struct {
+	Name  []string       `yaml:"name"`
+	Flags []ManifestFlag `yaml:"flags"`
+}
+
+// ManifestFlag is contained in ManifestCommand.
+type ManifestFlag struct {
+	Name    string   `yaml:"name" json:"name"`
+	Aliases []string `yaml:"aliases" json:"aliases"`
+	// Type is one of 'bool', 'string' or 'list'.
+	Type string `yaml:"type" json:"type"`
+	Help string `yaml:"help" json:"help"`
+}
+
+var manifestNameRegex = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// flagTypes maps manifest flag types to their ModuleFlag representation.
+var flagTypes = map[string]rune{"bool": 'b', "string": 's', "list": 'l'}
+
+// Validate ensures the manifest describes a usable module.
+func (m *Manifest) Validate() error {
+	var errs []error
+	if !manifestNameRegex.MatchString(m.Name) {
+		errs = append(errs, fmt.Errorf("name '%s' must be lowercase alphanumeric", m.Name))
+	}
+	for _, alias := range m.Aliases {
+		if !manifestNameRegex.MatchString(alias) {
+			errs = append(errs, fmt.Errorf("alias '%s' must be lowercase alphanumeric", alias))
+		}
+	}
+	if len(m.Exec) == 0 || m.Exec[0] == "" {
+		errs = append(errs, errors.New("exec must not be empty"))
+	}
+	for _, variable := range m.Env {
+		if !strings.Contains(variable, "=") {
+			errs = append(errs, fmt.Errorf("env '%s' is not in 'KEY=VALUE' format", variable))
+		}
+	}
+	for _, variable := range m.InheritEnv {
+		if variable == "" || strings.Contains(variable, "=") {
+			errs = append(errs, fmt.Errorf("inherit_env '%s' is not a variable name", variable))
+		}
+	}
+	if len(m.Commands) == 0 {
+		errs = append(errs, errors.New("commands must not be empty"))
+	}
+	for _, command := range m.Commands {
+		if len(command.Name) == 0 || command.Name[0] != m.Name {
+			errs = append(errs, fmt.Errorf("command '%s' must start with module name", strings.Join(command.Name, " ")))
+		}
+		for _, flag := range command.Flags {
+			if _, ok := flagTypes[flag.Type]; !ok || flag.Name == "" {
+				errs = append(errs, fmt.Errorf("flag '%s' has unknown type '%s'", flag.Name, flag.Type))
+			}
+		}
+	}
+	if len(m.ArchiveCommand) > 0 {
+		found := false
+		for _, command := range m.Commands {
+			if strings.Join(command.Name, " ") == strings.Join(m.ArchiveCommand, " ") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("archive command '%s' is not listed in commands", strings.Join(m.ArchiveCommand, " ")))
+		}
+		if !strings.HasPrefix(m.ContentType, "application/vnd.redhat.") {
+			errs = append(errs, fmt.Errorf("content type '%s' is not an Insights content type", m.ContentType))
+		}
+	}
+	if m.Protocol < 0 || m.Protocol > ProtocolVersion {
+		errs = append(errs, fmt.Errorf("protocol version %d is not supported", m.Protocol))
+	}
+	if m.MinimumClientVersion != "" && internal.Version != "development" {
+		if compareVersions(internal.Version, m.MinimumClientVersion) < 0 {
+			errs = append(errs, fmt.Errorf("client version %s or newer is required", m.MinimumClientVersion))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Module converts the manifest into a Module.
+func (m *Manifest) Module() *Module {
+	module := &Module{
+		Name:               m.Name,
+		Aliases:            m.Aliases,
+		Version:            m.Version,
+		Exec:               m.Exec,
+		Env:                m.Env,
+		InheritEnv:         m.InheritEnv,
+		Privileges:         m.Privileges,
+		Protocol:           m.Protocol,
+		ArchiveCommandName: m.ArchiveCommand,
+		ArchiveContentType: m.ContentType,
+	}
+	for _, command := range m.Commands {
+		moduleCommand := ModuleCommand{Name: command.Name}
+		for _, flag := range command.Flags {
+			moduleCommand.Flags = append(moduleCommand.Flags, ModuleFlag{
+				Name: flag.Name, Aliases: flag.Aliases, Type: flagTypes[flag.Type], Help: flag.Help,
+			})
+		}
+		module.Commands = append(module.Commands, moduleCommand)
+	}
+	return module
+}
+
+// ReadManifest loads a manifest from a file and validates it.
+func ReadManifest(path string) (*Manifest, IError) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, NewError(ErrManifest, err, fmt.Sprintf("Could not read module manifest '%s'.", path))
+	}
+
+	var manifest Manifest
+	if err = yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, NewError(ErrManifest, err, fmt.Sprintf("Module manifest '%s' is malformed.", path))
+	}
+	if err = manifest.Validate(); err != nil {
+		return nil, NewError(ErrManifest, err, fmt.Sprintf("Module manifest '%s' is not valid.", path))
+	}
+	return &manifest, nil
+}
+
+// readManifests loads all valid manifests from a directory in lexical order.
+//
+// Invalid manifests are logged and skipped. Like hooks, the manifests and the directory
+// have to be owned by ModuleDirectoryOwner and must not be writable by other users,
+// as the modules they define are run as root.
+func readManifests(directory string) []*Manifest {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("could not list module manifests", slog.String("directory", directory), slog.String("error", err.Error()))
+		}
+		return nil
+	}
+	if err = internal.CheckOwnership(directory, ModuleDirectoryOwner); err != nil {
+		slog.Error("refusing insecure module manifests", slog.String("directory", directory), slog.String("error", err.Error()))
+		return nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		switch filepath.Ext(entry.Name()) {
+		case ".yaml", ".yml", ".json":
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+
+	var manifests []*Manifest
+	for _, name := range names {
+		if err = internal.CheckOwnership(filepath.Join(directory, name), ModuleDirectoryOwner); err != nil {
+			slog.Error("refusing insecure module manifest", slog.String("file", name), slog.String("error", err.Error()))
+			continue
+		}
+		manifest, err := ReadManifest(filepath.Join(directory, name))
+		if err != nil {
+			slog.Warn("ignoring module manifest", slog.String("file", name), slog.String("error", err.Error()))
+			continue
+		}
+		slog.Debug("module manifest loaded", slog.String("file", name), slog.String("module", manifest.Name))
+		manifests = append(manifests, manifest)
+	}
+	return manifests
+}
+
+// compareVersions compares two dot-separated versions numerically.
+//
+// Non-numeric components are compared as strings.
+func compareVersions(a, b string) int {
+	left, right := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < max(len(left), len(right)); i++ {
+		l, r := "0", "0"
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+
+		ln, lErr := strconv.Atoi(l)
+		rn, rErr := strconv.Atoi(r)
+		if lErr != nil || rErr != nil {
+			if result := strings.Compare(l, r); result != 0 {
+				return result
+			}
+			continue
+		}
+		if ln < rn {
+			return -1
+		}
+		if ln > rn {
+			return 1
+		}
+	}
+	return 0
+}
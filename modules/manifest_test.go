@@ -0,0 +1,114 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadManifest(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Content string
+		Valid   bool
+	}{
+		{"yaml", `
+name: scanner
+exec: [/usr/libexec/scanner]
+env: [LC_ALL=C.UTF-8]
+commands:
+  - name: [scanner, collect]
+    flags:
+      - {name: profile, type: string}
+archive_command: [scanner, collect]
+content_type: application/vnd.redhat.scanner.collection
+`, true},
+		{"json", `{"name": "scanner", "exec": ["scanner"], "commands": [{"name": ["scanner", "collect"]}]}`, true},
+		{"no exec", `{"name": "scanner", "commands": [{"name": ["scanner", "collect"]}]}`, false},
+		{"bad name", `{"name": "Scanner", "exec": ["scanner"], "commands": [{"name": ["Scanner", "collect"]}]}`, false},
+		{"foreign command", `{"name": "scanner", "exec": ["scanner"], "commands": [{"name": ["advisor", "collect"]}]}`, false},
+		{"bad flag type", `{"name": "scanner", "exec": ["scanner"], "commands": [{"name": ["scanner", "collect"], "flags": [{"name": "x", "type": "int"}]}]}`, false},
+		{"unlisted archive command", `{"name": "scanner", "exec": ["scanner"], "commands": [{"name": ["scanner", "collect"]}], "archive_command": ["scanner", "run"], "content_type": "application/vnd.redhat.scanner.collection"}`, false},
+		{"bad content type", `{"name": "scanner", "exec": ["scanner"], "commands": [{"name": ["scanner", "collect"]}], "archive_command": ["scanner", "collect"], "content_type": "text/plain"}`, false},
+		{"bad env", `{"name": "scanner", "exec": ["scanner"], "env": ["LC_ALL"], "commands": [{"name": ["scanner", "collect"]}]}`, false},
+		{"malformed", `name: [`, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "module.yaml")
+			if err := os.WriteFile(path, []byte(test.Content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			manifest, err := ReadManifest(path)
+			if test.Valid && err != nil {
+				t.Fatalf("expected 'nil', got '%v'", err)
+			}
+			if !test.Valid && err == nil {
+				t.Fatalf("expected error, got '%+v'", manifest)
+			}
+		})
+	}
+}
+
+func TestReadManifests_ownership(t *testing.T) {
+	owner := ModuleDirectoryOwner
+	t.Cleanup(func() { ModuleDirectoryOwner = owner })
+	manifest := []byte(`{"name": "scanner", "exec": ["scanner"], "commands": [{"name": ["scanner", "collect"]}]}`)
+
+	tests := []struct {
+		Name          string
+		DirectoryMode os.FileMode
+		FileMode      os.FileMode
+		Owner         int
+		Loaded        bool
+	}{
+		{"secure", 0o755, 0o644, os.Geteuid(), true},
+		{"world-writable file", 0o755, 0o646, os.Geteuid(), false},
+		{"group-writable file", 0o755, 0o664, os.Geteuid(), false},
+		{"world-writable directory", 0o777, 0o644, os.Geteuid(), false},
+		{"foreign owner", 0o755, 0o644, os.Geteuid() + 1, false},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			directory := t.TempDir()
+			path := filepath.Join(directory, "scanner.json")
+			if err := os.WriteFile(path, manifest, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(path, test.FileMode); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(directory, test.DirectoryMode); err != nil {
+				t.Fatal(err)
+			}
+			ModuleDirectoryOwner = test.Owner
+
+			if manifests := readManifests(directory); (len(manifests) == 1) != test.Loaded {
+				t.Errorf("expected loaded '%v', got '%v'", test.Loaded, manifests)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		A, B   string
+		Result int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.0", "1.0.0", 0},
+		{"1.2.0", "1.10.0", -1},
+		{"2.0", "1.99.99", 1},
+		{"1.0.0a", "1.0.0b", -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.A+" "+test.B, func(t *testing.T) {
+			if result := compareVersions(test.A, test.B); result != test.Result {
+				t.Errorf("expected '%d', got '%d'", test.Result, result)
+			}
+		})
+	}
+}
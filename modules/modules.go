@@ -1,22 +1,31 @@
 package modules
 
 import (
-	"bytes"
+	"bufio"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/exec"
-	"path"
+	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+
+	"golang.org/x/sys/unix"
 )
 
 type ModuleFlag struct {
 	Name    string
 	Aliases []string
 	Type    rune
+	Help    string
 }
 
 type ModuleCommand struct {
@@ -27,12 +36,20 @@ type ModuleCommand struct {
 type Module struct {
 	// Name is human- and machine-readable name in lowercase.
 	Name string
+	// Aliases are alternative names the module can be selected with.
+	Aliases []string
 	// Version is the package version.
 	Version string
 	// Exec is a path to a binary which should be executed, followed by flags that are always set.
 	Exec []string
 	// Env is a list of environment variables that are always set.
 	Env []string
+	// InheritEnv lists variables passed from the client in addition to InheritedEnvironment.
+	InheritEnv []string
+	// Privileges reduce privileges of the module. Nil means the module runs as root.
+	Privileges *Privileges
+	// Protocol is the module protocol version the module supports. Zero if it does not support it.
+	Protocol int
 
 	Commands []ModuleCommand
 
@@ -40,27 +57,181 @@ type Module struct {
 	ArchiveCommandName []string
 	// ArchiveContentType is used as an HTTP Content-Type for uploaded data archive.
 	ArchiveContentType string
+
+	// described is set once the module has been asked to describe itself.
+	described bool
+	// describeErr is the result of the handshake, returned by Describe once the module is described.
+	describeErr IError
+	// versionFallback is used to obtain a version when the module cannot describe itself.
+	versionFallback func() string
+	// core is set for modules implemented by the Core. They are run with PYTHONPATH set to the Core egg.
+	core bool
+	// eggs locate the Core eggs of Core modules; SystemEggs are used when it is not set.
+	eggs *Eggs
+	// builtin implements the commands of modules written in Go. Exec is not used when it is set.
+	builtin func(ctx context.Context, command, args []string, options *RunOptions) IError
 }
 
+var registryLock sync.Mutex
+var registryInitialized = false
+var registry []*Module
+
+// ClearModules clears the module registry cached in memory.
+func ClearModules() {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registryInitialized = false
+}
+
+// GetModules returns built-in modules and modules defined by manifests.
+//
+// Manifests from ModuleDirectories override built-in modules of the same name.
+// The registry is cached internally, call ClearModules to force reload.
+// Copies of the modules are returned; changes made by Describe are stored in the registry.
+// It is safe for concurrent use.
 func GetModules() []*Module {
-	return []*Module{
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if !registryInitialized {
+		registry = loadModules()
+		registryInitialized = true
+	}
+
+	copies := make([]*Module, len(registry))
+	for i, module := range registry {
+		copied := *module
+		copies[i] = &copied
+	}
+	return copies
+}
+
+// registeredModule returns a copy of the registered module of the name, or nil.
+func registeredModule(name string) *Module {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if !registryInitialized {
+		return nil
+	}
+	for _, module := range registry {
+		if module.Name == name {
+			copied := *module
+			return &copied
+		}
+	}
+	return nil
+}
+
+// registerDescribed replaces the registered module of the same name by a copy of the described one.
+func registerDescribed(described *Module) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if !registryInitialized {
+		return
+	}
+	for i, module := range registry {
+		if module.Name == described.Name {
+			copied := *described
+			registry[i] = &copied
+			return
+		}
+	}
+}
+
+// loadModules creates built-in modules and reads the manifests.
+func loadModules() []*Module {
+	modules := []*Module{
 		GetAdvisorModule(),
 		GetComplianceModule(),
 		GetMalwareModule(),
+		GetBasicModule(),
+	}
+	for _, directory := range ModuleDirectories {
+		for _, manifest := range readManifests(directory) {
+			module := manifest.Module()
+			replaced := -1
+			for i, existing := range modules {
+				if existing.Name == module.Name {
+					replaced = i
+					break
+				}
+			}
+			if err := checkModuleNames(modules, replaced, module); err != nil {
+				slog.Warn("ignoring module manifest", slog.String("module", module.Name), slog.String("error", err.Error()))
+				continue
+			}
+			if replaced >= 0 {
+				slog.Debug("module overridden by manifest", slog.String("name", module.Name))
+				modules[replaced] = module
+			} else {
+				modules = append(modules, module)
+			}
+		}
+	}
+
+	return modules
+}
+
+// checkModuleNames ensures the name and aliases of the module do not select another module.
+//
+// The module at index `replaced` is overridden by the module, its names are not considered.
+func checkModuleNames(modules []*Module, replaced int, module *Module) error {
+	for i, existing := range modules {
+		if i == replaced {
+			continue
+		}
+		names := append([]string{existing.Name}, existing.Aliases...)
+		if slices.Contains(existing.Aliases, module.Name) {
+			return fmt.Errorf("name '%s' is an alias of module '%s'", module.Name, existing.Name)
+		}
+		for _, alias := range module.Aliases {
+			if slices.Contains(names, alias) {
+				return fmt.Errorf("alias '%s' is already used by module '%s'", alias, existing.Name)
+			}
+		}
 	}
+	return nil
 }
 
+// GetModule returns a module by its name or alias.
 func GetModule(name string) (*Module, IError) {
 	for _, module := range GetModules() {
 		if module.Name == name {
 			return module, nil
 		}
+		for _, alias := range module.Aliases {
+			if alias == name {
+				return module, nil
+			}
+		}
 	}
 	return nil, NewError(ErrNoModule, nil, fmt.Sprintf("Module not found: %s", name))
 }
 
+// GetModuleByCommand returns a module implementing the command.
+//
+// Commands of modules are named after the module. When the command is not declared, the module
+// of that name is described, as the command may only be known from its description.
 func GetModuleByCommand(command []string) (found *Module, ok bool) {
-	for _, module := range GetModules() {
+	if module, ok := findModuleByCommand(GetModules(), command); ok {
+		return module, true
+	}
+	if len(command) == 0 {
+		return nil, false
+	}
+	module, err := GetModule(command[0])
+	if err != nil || module.described {
+		return nil, false
+	}
+	if err := module.Describe(); err != nil {
+		slog.Debug("module could not be described", slog.String("name", module.Name), slog.String("error", err.Error()))
+		return nil, false
+	}
+	return findModuleByCommand([]*Module{module}, command)
+}
+
+// findModuleByCommand returns the first of the modules that implements the command.
+func findModuleByCommand(modules []*Module, command []string) (*Module, bool) {
+	for _, module := range modules {
 		for _, cmd := range module.Commands {
 			if reflect.DeepEqual(cmd.Name, command) {
 				return module, true
@@ -78,57 +249,370 @@ func CommandExists(command []string) bool {
 // CreateArchiveDirectory creates a new directory with semi-random name at `parent`
 // with permissions 750.
 func CreateArchiveDirectory(parent string) (string, IError) {
-	directory := path.Join(parent, fmt.Sprintf("archive-%d", time.Now().Unix()))
-	if err := os.Mkdir(directory, 0o750); err != nil {
+	directory, err := os.MkdirTemp(parent, fmt.Sprintf("archive-%d-*", time.Now().Unix()))
+	if err != nil {
+		return "", NewError(ErrRun, err, "Could not prepare archive directory.")
+	}
+	if err = os.Chmod(directory, 0o750); err != nil {
 		return "", NewError(ErrRun, err, "Could not prepare archive directory.")
 	}
 	return directory, nil
 }
 
+// RunOptions configures the execution of a module command.
+type RunOptions struct {
+	// Stdout receives the standard output of the module. If nil, the output is logged.
+	Stdout io.Writer
+	// OnProgress is called for each progress event emitted by the module.
+	OnProgress func(ProgressEvent)
+
+	// Context terminates the module when it is done.
+	Context context.Context
+	// Timeout terminates the module when it runs for too long. Zero means no limit.
+	Timeout time.Duration
+	// KillTimeout is a delay between sending SIGTERM and SIGKILL to the module.
+	KillTimeout time.Duration
+	// Limits runs the module in a transient systemd scope. Nil means no limits.
+	Limits *ResourceLimits
+
+	// Environment overrides variables of the module environment.
+	Environment map[string]string
+	// DropPrivileges enables privilege reduction of modules that declare Privileges.
+	DropPrivileges bool
+	// DefaultUser is used by modules that declare Privileges without a user.
+	DefaultUser string
+	// SharedDirectory stays visible to modules with private temporary directories, see Collect.
+	SharedDirectory string
+}
+
+// ProgressEvent is emitted by a module as a JSON line.
+//
+// Modules write the events to the file descriptor passed in ProgressFDVariable.
+type ProgressEvent struct {
+	// Type is an event type, e.g. `progress` or `message`.
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	// Percent is an optional completion percentage.
+	Percent *float64 `json:"percent,omitempty"`
+}
+
+// ProgressFDVariable is an environment variable containing the number of the progress file descriptor.
+const ProgressFDVariable = "INSIGHTS_PROGRESS_FD"
+
+// stderrTail is the number of lines of the standard error output included in errors.
+const stderrTail = 20
+
 // RunCommand executes module command.
 //
 // The shell command is constructed as `.Exec + command + args`.
-func (m *Module) RunCommand(command, args []string) IError {
-	var stdout, stderr bytes.Buffer
-	argv := m.Exec
+// Standard error output is logged line by line as it is produced.
+func (m *Module) RunCommand(command, args []string, options *RunOptions) IError {
+	if options == nil {
+		options = &RunOptions{}
+	}
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	if m.builtin != nil {
+		slog.Debug("running built-in module", slog.String("name", m.Name), slog.String("command", strings.Join(command, " ")))
+		return m.builtin(ctx, command, args, options)
+	}
+
+	argv := append([]string{}, m.Exec...)
 	argv = append(argv, command...)
 	argv = append(argv, args...)
 
+	environment := map[string]string{}
+	for name, value := range options.Environment {
+		environment[name] = value
+	}
+	if options.DropPrivileges && m.Privileges != nil {
+		creds, err := m.Privileges.resolve(options.DefaultUser)
+		if err != nil {
+			return err
+		}
+		argv = m.Privileges.wrap(creds, argv)
+		if m.Privileges.PrivateTmp {
+			argv = isolateTmp(argv, options.SharedDirectory)
+		}
+	}
+	if options.Limits != nil {
+		argv = options.Limits.wrap(m.Name, argv)
+	}
+
 	cmd := exec.Command(argv[0], argv[1:]...)
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	cmd.Env = m.Env
+	cmd.Env = m.Environment(environment)
+	// The module and all its children are placed into their own process group,
+	// so they can be terminated together.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	var wg sync.WaitGroup
+	var stderrLines []string
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return NewError(ErrRun, err, "Could not run module command.")
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			slog.Info("module stderr", slog.String("name", m.Name), slog.String("line", scanner.Text()))
+			stderrLines = append(stderrLines, scanner.Text())
+			if len(stderrLines) > stderrTail {
+				stderrLines = stderrLines[1:]
+			}
+		}
+	}()
+
+	if options.Stdout != nil {
+		cmd.Stdout = options.Stdout
+	} else {
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return NewError(ErrRun, err, "Could not run module command.")
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			scanner := bufio.NewScanner(stdout)
+			for scanner.Scan() {
+				slog.Debug("module stdout", slog.String("name", m.Name), slog.String("line", scanner.Text()))
+			}
+		}()
+	}
+
+	// The progress pipe is always passed, so modules can rely on its existence.
+	progressReader, progressWriter, err := os.Pipe()
+	if err != nil {
+		return NewError(ErrRun, err, "Could not run module command.")
+	}
+	cmd.ExtraFiles = []*os.File{progressWriter}
+	cmd.Env = append(append([]string{}, cmd.Env...), fmt.Sprintf("%s=%d", ProgressFDVariable, 3))
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer progressReader.Close()
+		readProgressEvents(m.Name, progressReader, options.OnProgress)
+	}()
 
 	slog.Debug(
 		"running module",
 		slog.String("name", m.Name),
 		slog.String("version", m.Version),
 		slog.String("command", strings.Join(argv, " ")),
-		slog.String("environment", strings.Join(m.Env, " ")),
+		slog.String("environment", strings.Join(cmd.Env, " ")),
 	)
 
-	err := cmd.Run()
+	err = cmd.Start()
+	// The child has its own copy of the descriptor; close ours to receive EOF once the module exits.
+	_ = progressWriter.Close()
 	if err != nil {
-		slog.Error("module failed", slog.String("error", err.Error()))
-		return NewError(
-			ErrRun,
-			errors.Join(err, errors.New(stderr.String())),
-			"Could not run module command.",
-		)
+		_ = stderr.Close()
+		wg.Wait()
+		slog.Error("module could not be started", slog.String("error", err.Error()))
+		return NewError(ErrRun, err, "Could not run module command.")
 	}
 
-	slog.Debug("module finished")
-	return nil
+	// The module is not reaped until the client is done with its process group:
+	// while it is a zombie, its PID cannot be reused and the group can be signaled safely.
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		waitExited(cmd.Process.Pid)
+	}()
+	done := make(chan struct{})
+	terminated := make(chan struct{})
+	// killedByClient is only read once the terminating goroutine has finished.
+	killedByClient := false
+	go func() {
+		defer close(terminated)
+		select {
+		case <-done:
+		case <-ctx.Done():
+			killedByClient = true
+			terminateProcessGroup(cmd.Process.Pid, options.KillTimeout, exited)
+		}
+	}()
+
+	<-exited
+	wg.Wait()
+	close(done)
+	<-terminated
+	err = cmd.Wait()
+	if err == nil {
+		slog.Debug("module finished")
+		return nil
+	}
+
+	slog.Error("module failed", slog.String("error", err.Error()))
+	original := errors.Join(err, errors.New(strings.Join(stderrLines, "\n")))
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return NewError(ErrTimeout, original, fmt.Sprintf("Module command did not finish in %s.", options.Timeout))
+	case errors.Is(ctx.Err(), context.Canceled):
+		return NewError(ErrCanceled, original, "Module command was canceled.")
+	case options.Limits != nil && !killedByClient && wasKilled(err):
+		return NewError(ErrResourceLimit, original, "Module command was killed for exceeding its resource limits.")
+	}
+	return NewError(ErrRun, original, "Could not run module command.")
+}
+
+// waitExited blocks until the process exits, without reaping it.
+func waitExited(pid int) {
+	var info unix.Siginfo
+	for {
+		err := unix.Waitid(unix.P_PID, pid, &info, unix.WEXITED|unix.WNOWAIT, nil)
+		if !errors.Is(err, unix.EINTR) {
+			if err != nil {
+				slog.Warn("could not wait for module", slog.Int("pid", pid), slog.String("error", err.Error()))
+			}
+			return
+		}
+	}
+}
+
+// terminateProcessGroup asks the process group to terminate, then kills it.
+//
+// SIGKILL is sent when the process does not exit within the timeout. The process must not
+// have been reaped yet, otherwise its PID could belong to an unrelated process group.
+func terminateProcessGroup(pid int, timeout time.Duration, exited <-chan struct{}) {
+	slog.Warn("terminating module", slog.Int("pid", pid))
+	_ = syscall.Kill(-pid, syscall.SIGTERM)
+
+	select {
+	case <-exited:
+	case <-time.After(timeout):
+		slog.Warn("killing module", slog.Int("pid", pid))
+	}
+	// Children of the module may still be running, even when the module itself has exited.
+	_ = syscall.Kill(-pid, syscall.SIGKILL)
+}
+
+// wasKilled reports whether the process was terminated by SIGKILL.
+//
+// Callers have to rule out SIGKILL sent by the client itself.
+func wasKilled(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGKILL
+}
+
+// readProgressEvents parses JSON lines from the reader until it is closed.
+//
+// Malformed lines are logged and skipped.
+func readProgressEvents(name string, reader io.Reader, callback func(ProgressEvent)) {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		var event ProgressEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			slog.Warn("ignoring malformed progress event", slog.String("name", name), slog.String("line", scanner.Text()))
+			continue
+		}
+		slog.Debug("module progress", slog.String("name", name), slog.String("type", event.Type), slog.String("message", event.Message))
+		if callback != nil {
+			callback(event)
+		}
+	}
 }
 
 // Collect runs the module's collection command.
 //
-// `directory` has to exist and has to be writable.
-func (m *Module) Collect(directory string, args []string) IError {
+// `directory` has to exist and has to be writable. It is shared with the module even when
+// the module runs with private temporary directories.
+func (m *Module) Collect(directory string, args []string, options *RunOptions) IError {
 	if len(m.ArchiveCommandName) == 0 {
 		return NewError(ErrRun, nil, "Module does not have collection capabilities.")
 	}
+	collectOptions := RunOptions{}
+	if options != nil {
+		collectOptions = *options
+	}
+	collectOptions.SharedDirectory = directory
+	options = &collectOptions
+
+	// Unprivileged modules have to be able to write into the archive directory.
+	if options.DropPrivileges && m.Privileges != nil {
+		creds, err := m.Privileges.resolve(options.DefaultUser)
+		if err != nil {
+			return err
+		}
+		if err := os.Chown(directory, creds.uid, creds.gid); err != nil {
+			return NewError(ErrRun, err, "Could not prepare archive directory.")
+		}
+	}
 
 	args = append(args, fmt.Sprintf("--archive=%s", directory))
-	return m.RunCommand(m.ArchiveCommandName, args)
+	if m.core {
+		return m.collectWithFallback(directory, args, options)
+	}
+	return m.RunCommand(m.ArchiveCommandName, args, options)
+}
+
+// collectWithFallback runs the collection with the Core eggs in order of preference.
+//
+// When the module fails with an egg, the collection is retried with the next one. The newest egg
+// is promoted to the last stable one when it succeeds, and is discarded when it fails.
+// Timeouts, cancellation and resource limits are not caused by the egg, they are returned right away.
+func (m *Module) collectWithFallback(directory string, args []string, options *RunOptions) IError {
+	if options == nil {
+		options = &RunOptions{}
+	}
+	coreEggs := m.coreEggs()
+	eggs := coreEggs.Candidates()
+
+	var err IError
+	for i, egg := range eggs {
+		eggOptions := *options
+		eggOptions.Environment = map[string]string{"PYTHONPATH": corePythonPath(egg)}
+		for name, value := range options.Environment {
+			eggOptions.Environment[name] = value
+		}
+
+		slog.Debug("collecting with egg", slog.String("name", m.Name), slog.String("egg", egg))
+		err = m.RunCommand(m.ArchiveCommandName, args, &eggOptions)
+		if err == nil {
+			if egg == coreEggs.NewestPath {
+				coreEggs.promote()
+			}
+			return nil
+		}
+		if !err.Is(ErrRun) || i == len(eggs)-1 {
+			return err
+		}
+
+		slog.Warn("collection failed, falling back to another egg", slog.String("egg", egg), slog.String("fallback", eggs[i+1]))
+		if egg == coreEggs.NewestPath {
+			coreEggs.discard()
+		}
+		if err := clearDirectory(directory); err != nil {
+			return NewError(ErrRun, err, "Could not prepare archive directory.")
+		}
+	}
+	return err
+}
+
+// clearDirectory removes the content of the directory.
+func clearDirectory(directory string) error {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(directory, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
 }
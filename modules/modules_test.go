@@ -0,0 +1,157 @@
+package modules
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m-horky/insights-client-next/internal/testutil"
+)
+
+func TestModule_RunCommand(t *testing.T) {
+	script := testutil.WriteScript(t, filepath.Join(t.TempDir(), "module"), `echo "output"
+echo "warning" >&2
+echo '{"type": "progress", "message": "Collecting.", "percent": 50}' >&$INSIGHTS_PROGRESS_FD
+echo 'malformed' >&$INSIGHTS_PROGRESS_FD
+exit "$2"
+`)
+	module := &Module{Name: "scanner", Exec: []string{script}}
+
+	var stdout bytes.Buffer
+	var events []ProgressEvent
+	options := &RunOptions{Stdout: &stdout, OnProgress: func(event ProgressEvent) { events = append(events, event) }}
+
+	if err := module.RunCommand([]string{"scanner"}, []string{"0"}, options); err != nil {
+		t.Fatalf("expected 'nil', got '%v'", err)
+	}
+	if stdout.String() != "output\n" {
+		t.Errorf("expected 'output', got '%s'", stdout.String())
+	}
+	if len(events) != 1 || events[0].Message != "Collecting." || *events[0].Percent != 50 {
+		t.Errorf("expected one progress event, got '%+v'", events)
+	}
+
+	err := module.RunCommand([]string{"scanner"}, []string{"1"}, nil)
+	if err == nil {
+		t.Fatalf("expected error, got 'nil'")
+	}
+	if !bytes.Contains([]byte(err.Error()), []byte("warning")) {
+		t.Errorf("expected standard error output in error, got '%v'", err)
+	}
+}
+
+func TestModule_RunCommand_timeout(t *testing.T) {
+	script := testutil.WriteScript(t, filepath.Join(t.TempDir(), "module"), "trap '' TERM\nsleep 30 &\nwait\n")
+	module := &Module{Name: "scanner", Exec: []string{script}}
+
+	started := time.Now()
+	err := module.RunCommand(
+		[]string{"scanner"}, nil, &RunOptions{Timeout: 100 * time.Millisecond, KillTimeout: 100 * time.Millisecond},
+	)
+	if err == nil || !err.Is(ErrTimeout) {
+		t.Fatalf("expected timeout error, got '%v'", err)
+	}
+	if time.Since(started) > 5*time.Second {
+		t.Errorf("module was not killed in time")
+	}
+}
+
+func TestModule_Environment(t *testing.T) {
+	t.Setenv("LANG", "C.UTF-8")
+	t.Setenv("SECRET", "password")
+	t.Setenv("SCANNER_DEBUG", "1")
+
+	module := &Module{Name: "scanner", Env: []string{"LANG=en_US.UTF-8", "MODE=fast"}, InheritEnv: []string{"SCANNER_DEBUG"}}
+	env := module.Environment(map[string]string{"MODE": "slow"})
+
+	expected := map[string]string{"LANG": "en_US.UTF-8", "MODE": "slow", "SCANNER_DEBUG": "1"}
+	found := map[string]string{}
+	for _, variable := range env {
+		name, value, _ := strings.Cut(variable, "=")
+		found[name] = value
+	}
+	for name, value := range expected {
+		if found[name] != value {
+			t.Errorf("expected '%s=%s', got '%s=%s'", name, value, name, found[name])
+		}
+	}
+	if _, ok := found["SECRET"]; ok {
+		t.Errorf("expected 'SECRET' not to be inherited")
+	}
+}
+
+func TestGetModules_copies(t *testing.T) {
+	directories := ModuleDirectories
+	t.Cleanup(func() {
+		ModuleDirectories = directories
+		ClearModules()
+	})
+	ModuleDirectories = nil
+	ClearModules()
+
+	first, _ := GetModule("advisor")
+	first.Version = "described"
+	if second, _ := GetModule("advisor"); second.Version == first.Version {
+		t.Error("expected the registry not to be modified through a returned module")
+	}
+	if first.Protocol != 0 {
+		t.Errorf("expected Core modules not to declare the module protocol, got %d", first.Protocol)
+	}
+}
+
+func TestGetModules_aliasCollision(t *testing.T) {
+	directory := t.TempDir()
+	manifests := map[string]string{
+		"10-scanner.yaml":  `{"name": "scanner", "aliases": ["scan"], "exec": ["scanner"], "commands": [{"name": ["scanner", "collect"]}]}`,
+		"20-alias.yaml":    `{"name": "other", "aliases": ["scan"], "exec": ["other"], "commands": [{"name": ["other", "collect"]}]}`,
+		"30-builtin.yaml":  `{"name": "thief", "aliases": ["advisor"], "exec": ["thief"], "commands": [{"name": ["thief", "collect"]}]}`,
+		"40-name.yaml":     `{"name": "malware-detection", "exec": ["x"], "commands": [{"name": ["malware-detection", "collect"]}]}`,
+		"50-override.yaml": `{"name": "malware", "aliases": ["malware-detection"], "exec": ["malware"], "commands": [{"name": ["malware", "collect"]}]}`,
+	}
+	for name, content := range manifests {
+		if err := os.WriteFile(filepath.Join(directory, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	directories, owner := ModuleDirectories, ModuleDirectoryOwner
+	t.Cleanup(func() {
+		ModuleDirectories, ModuleDirectoryOwner = directories, owner
+		ClearModules()
+	})
+	ModuleDirectories, ModuleDirectoryOwner = []string{directory}, os.Geteuid()
+	ClearModules()
+
+	tests := []struct {
+		Name     string
+		Expected string
+	}{
+		{"scan", "scanner"},
+		{"advisor", "advisor"},
+		{"malware-detection", "malware"},
+		{"other", ""},
+		{"thief", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			module, err := GetModule(test.Name)
+			if test.Expected == "" {
+				if err == nil || !err.Is(ErrNoModule) {
+					t.Fatalf("expected '%v', got '%v'", ErrNoModule, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected 'nil', got '%v'", err)
+			}
+			if module.Name != test.Expected {
+				t.Errorf("expected '%s', got '%s'", test.Expected, module.Name)
+			}
+		})
+	}
+	if module, _ := GetModule("malware"); module.Exec[0] != "malware" {
+		t.Errorf("expected built-in module to be overridden, got '%v'", module.Exec)
+	}
+}
@@ -0,0 +1,89 @@
+package modules
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+)
+
+// Privileges are declared by modules that do not need to run as root.
+type Privileges struct {
+	// User the module runs as. If empty, RunOptions.DefaultUser is used.
+	User string `yaml:"user"`
+	// NoNewPrivileges prevents the module from gaining privileges, e.g. via setuid binaries.
+	NoNewPrivileges bool `yaml:"no_new_privileges"`
+	// DropCapabilities clears inheritable and bounding capability sets of the module.
+	DropCapabilities bool `yaml:"drop_capabilities"`
+	// PrivateTmp gives the module empty `/tmp` and `/var/tmp` in its own mount namespace.
+	PrivateTmp bool `yaml:"private_tmp"`
+}
+
+// credentials are resolved from Privileges.
+type credentials struct {
+	uid, gid int
+}
+
+// resolve looks up the user the module should run as.
+func (p *Privileges) resolve(defaultUser string) (*credentials, IError) {
+	name := p.User
+	if name == "" {
+		name = defaultUser
+	}
+	account, err := user.Lookup(name)
+	if err != nil {
+		return nil, NewError(ErrRun, err, fmt.Sprintf("Module user '%s' does not exist.", name))
+	}
+	uid, err := strconv.Atoi(account.Uid)
+	if err != nil {
+		return nil, NewError(ErrRun, err, fmt.Sprintf("Module user '%s' is not valid.", name))
+	}
+	gid, err := strconv.Atoi(account.Gid)
+	if err != nil {
+		return nil, NewError(ErrRun, err, fmt.Sprintf("Module user '%s' is not valid.", name))
+	}
+	return &credentials{uid: uid, gid: gid}, nil
+}
+
+// wrap prefixes the command so it runs with reduced privileges via setpriv(1).
+func (p *Privileges) wrap(creds *credentials, argv []string) []string {
+	wrapped := []string{
+		"setpriv",
+		fmt.Sprintf("--reuid=%d", creds.uid),
+		fmt.Sprintf("--regid=%d", creds.gid),
+		"--clear-groups",
+	}
+	if p.NoNewPrivileges {
+		wrapped = append(wrapped, "--no-new-privs")
+	}
+	if p.DropCapabilities {
+		wrapped = append(wrapped, "--inh-caps=-all", "--bounding-set=-all")
+	}
+	wrapped = append(wrapped, "--")
+	return append(wrapped, argv...)
+}
+
+// privateTmpMounts mounts empty temporary directories.
+const privateTmpMounts = `mount -t tmpfs -o mode=1777,nosuid,nodev tmpfs /tmp && ` +
+	`mount -t tmpfs -o mode=1777,nosuid,nodev tmpfs /var/tmp`
+
+// privateTmpScript mounts empty temporary directories and runs the command passed as arguments.
+const privateTmpScript = privateTmpMounts + ` && exec "$@"`
+
+// privateTmpSharedScript keeps the directory passed as the first argument visible, and runs the rest.
+//
+// The directory is opened before the temporary directories are mounted, as they may hide it,
+// and it is bind-mounted back into place afterward.
+const privateTmpSharedScript = `exec 9<"$1" && ` + privateTmpMounts + ` && ` +
+	`mkdir -p "$1" && mount --no-canonicalize --bind /proc/self/fd/9 "$1" && exec 9<&- && shift && exec "$@"`
+
+// isolateTmp prefixes the command so it runs in its own mount namespace via unshare(1).
+//
+// The temporary directories are mounted before the privileges are dropped by the command;
+// they are discarded together with the namespace once the module exits. The shared directory,
+// if set, stays visible even when it is placed in a temporary directory.
+func isolateTmp(argv []string, shared string) []string {
+	if shared == "" {
+		return append([]string{"unshare", "--mount", "--propagation=private", "--", "sh", "-c", privateTmpScript, "sh"}, argv...)
+	}
+	return append([]string{"unshare", "--mount", "--propagation=private", "--", "sh", "-c", privateTmpSharedScript, "sh", shared}, argv...)
+}
@@ -0,0 +1,48 @@
+package modules
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"testing"
+)
+
+func TestModule_Collect_privateTmp(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("mount namespaces require root")
+	}
+	if _, err := user.Lookup("nobody"); err != nil {
+		t.Skip("user 'nobody' does not exist")
+	}
+	if err := exec.Command("unshare", "--mount", "true").Run(); err != nil {
+		t.Skipf("mount namespaces are not available: %v", err)
+	}
+
+	// The archive directory is placed in /tmp, which is replaced in the namespace of the module.
+	archive := t.TempDir()
+	if err := os.Chmod(filepath.Dir(archive), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	marker := filepath.Join(filepath.Dir(archive), "marker")
+	if err := os.WriteFile(marker, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Scripts placed in /tmp would be hidden as well, the module is passed inline.
+	script := `[ -e ` + marker + ` ] && exit 1
+[ "$(id -un)" = nobody ] || exit 2
+touch "${3#--archive=}/collected"`
+	module := &Module{
+		Name:               "scanner",
+		Exec:               []string{"sh", "-c", script, "sh"},
+		ArchiveCommandName: []string{"scanner", "collect"},
+		Privileges:         &Privileges{User: "nobody", PrivateTmp: true},
+	}
+
+	if err := module.Collect(archive, nil, &RunOptions{DropPrivileges: true}); err != nil {
+		t.Fatalf("expected 'nil', got '%v'", err)
+	}
+	if _, err := os.Stat(filepath.Join(archive, "collected")); err != nil {
+		t.Errorf("expected the module to collect into the archive directory, got '%v'", err)
+	}
+}
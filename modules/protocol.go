@@ -0,0 +1,213 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProtocolVersion is the newest version of the module protocol the client understands.
+//
+// Modules declaring protocol support are invoked as `.Exec describe --protocol=VERSION`
+// and are expected to print Description as JSON to the standard output.
+const ProtocolVersion = 1
+
+// describeTimeout limits how long the module may take to describe itself.
+var describeTimeout = 30 * time.Second
+
+// Description is returned by modules invoked with the `describe` verb.
+type Description struct {
+	Protocol    int                  `json:"protocol"`
+	Version     string               `json:"version"`
+	ContentType string               `json:"content_type"`
+	Commands    []DescriptionCommand `json:"commands"`
+}
+
+// DescriptionCommand is contained in Description.
+type DescriptionCommand struct {
+	Name  []string       `json:"name"`
+	Flags []ManifestFlag `json:"flags"`
+}
+
+// describeLock ensures each registered module is only described once.
+var describeLock sync.Mutex
+
+// Describe performs the capability handshake with the module.
+//
+// The module version, content type and command flags are updated from its description.
+// Modules without protocol support only get their version from the fallback.
+// The described module is stored in the registry, so copies returned by GetModules later
+// are already described and the handshake is only performed once.
+func (m *Module) Describe() IError {
+	if m.described {
+		return m.describeErr
+	}
+	describeLock.Lock()
+	defer describeLock.Unlock()
+	if registered := registeredModule(m.Name); registered != nil && registered.described {
+		*m = *registered
+		return m.describeErr
+	}
+
+	m.described = true
+	if m.Protocol == 0 {
+		if m.versionFallback != nil {
+			m.Version = m.versionFallback()
+		}
+	} else if err := m.describe(); err != nil && m.versionFallback != nil {
+		slog.Debug("module could not describe itself, using fallback", slog.String("name", m.Name))
+		m.Version = m.versionFallback()
+	} else {
+		m.describeErr = err
+	}
+	registerDescribed(m)
+	return m.describeErr
+}
+
+func (m *Module) describe() IError {
+	ctx, cancel := context.WithTimeout(context.Background(), describeTimeout)
+	defer cancel()
+
+	argv := append([]string{}, m.Exec...)
+	argv = append(argv, "describe", fmt.Sprintf("--protocol=%d", ProtocolVersion))
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = m.Environment(nil)
+
+	slog.Debug("describing module", slog.String("name", m.Name), slog.String("command", strings.Join(argv, " ")))
+	if err := cmd.Run(); err != nil {
+		slog.Error("module could not describe itself", slog.String("error", err.Error()), slog.String("stderr", stderr.String()))
+		return NewError(ErrProtocol, errors.Join(err, errors.New(stderr.String())), fmt.Sprintf("Module '%s' could not describe itself.", m.Name))
+	}
+
+	var description Description
+	if err := json.Unmarshal(stdout.Bytes(), &description); err != nil {
+		slog.Error("module description is malformed", slog.String("error", err.Error()), slog.String("stdout", stdout.String()))
+		return NewError(ErrProtocol, err, fmt.Sprintf("Module '%s' returned malformed description.", m.Name))
+	}
+	if err := description.validate(m.Name); err != nil {
+		slog.Error("module description is not valid", slog.String("error", err.Error()))
+		return NewError(ErrProtocol, err, fmt.Sprintf("Module '%s' returned unsupported description.", m.Name))
+	}
+
+	if description.Version != "" {
+		m.Version = description.Version
+	}
+	if description.ContentType != "" {
+		m.ArchiveContentType = description.ContentType
+	}
+	if len(description.Commands) > 0 {
+		m.Commands = nil
+		for _, command := range description.Commands {
+			moduleCommand := ModuleCommand{Name: command.Name}
+			for _, flag := range command.Flags {
+				moduleCommand.Flags = append(moduleCommand.Flags, ModuleFlag{
+					Name: flag.Name, Aliases: flag.Aliases, Type: flagTypes[flag.Type], Help: flag.Help,
+				})
+			}
+			m.Commands = append(m.Commands, moduleCommand)
+		}
+	}
+	slog.Debug("module described", slog.String("name", m.Name), slog.String("version", m.Version), slog.Int("protocol", description.Protocol))
+	return nil
+}
+
+// validate ensures the description can be used by this client.
+func (d *Description) validate(name string) error {
+	var errs []error
+	if d.Protocol < 1 || d.Protocol > ProtocolVersion {
+		errs = append(errs, fmt.Errorf("protocol version %d is not supported", d.Protocol))
+	}
+	for _, command := range d.Commands {
+		if len(command.Name) == 0 || command.Name[0] != name {
+			errs = append(errs, fmt.Errorf("command '%s' must start with module name", strings.Join(command.Name, " ")))
+		}
+		for _, flag := range command.Flags {
+			if _, ok := flagTypes[flag.Type]; !ok || flag.Name == "" {
+				errs = append(errs, fmt.Errorf("flag '%s' has unknown type '%s'", flag.Name, flag.Type))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AcceptsFlag reports whether the module command declares the flag.
+//
+// Modules implementing the protocol have to be described first, so the declared flags are known.
+func (m *Module) AcceptsFlag(command []string, name string) bool {
+	for _, cmd := range m.Commands {
+		if strings.Join(cmd.Name, " ") == strings.Join(command, " ") {
+			_, ok := findModuleFlag(cmd.Flags, name)
+			return ok
+		}
+	}
+	return false
+}
+
+// ValidateOptions ensures the options are accepted by the module command.
+//
+// Options are expected in `--name`, `--name=value` or `--name value` format.
+func (m *Module) ValidateOptions(command []string, options []string) IError {
+	var flags []ModuleFlag
+	found := false
+	for _, cmd := range m.Commands {
+		if strings.Join(cmd.Name, " ") == strings.Join(command, " ") {
+			flags = cmd.Flags
+			found = true
+			break
+		}
+	}
+	if !found {
+		return NewError(ErrNoModule, nil, fmt.Sprintf("Module '%s' does not implement command '%s'.", m.Name, strings.Join(command, " ")))
+	}
+
+	for i := 0; i < len(options); i++ {
+		option := options[i]
+		if !strings.HasPrefix(option, "-") {
+			return NewError(ErrProtocol, nil, fmt.Sprintf("Unexpected argument '%s'.", option))
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(option, "-"), "=")
+
+		flag, ok := findModuleFlag(flags, name)
+		if !ok {
+			return NewError(ErrProtocol, nil, fmt.Sprintf("Command '%s' does not accept flag '--%s'.", strings.Join(command, " "), name))
+		}
+		if flag.Type == 'b' {
+			if hasValue && value != "true" && value != "false" {
+				return NewError(ErrProtocol, nil, fmt.Sprintf("Flag '--%s' does not accept a value.", name))
+			}
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(options) {
+				return NewError(ErrProtocol, nil, fmt.Sprintf("Flag '--%s' requires a value.", name))
+			}
+			i++
+		}
+	}
+	return nil
+}
+
+func findModuleFlag(flags []ModuleFlag, name string) (ModuleFlag, bool) {
+	for _, flag := range flags {
+		if flag.Name == name {
+			return flag, true
+		}
+		for _, alias := range flag.Aliases {
+			if alias == name {
+				return flag, true
+			}
+		}
+	}
+	return ModuleFlag{}, false
+}
@@ -0,0 +1,117 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/m-horky/insights-client-next/internal/testutil"
+)
+
+// newDescribingModule creates a module which prints the description when run.
+func newDescribingModule(t *testing.T, description string) *Module {
+	script := testutil.WriteScript(t, filepath.Join(t.TempDir(), "module"), "cat <<'EOF'\n"+description+"\nEOF\n")
+	return &Module{
+		Name:     "scanner",
+		Exec:     []string{script},
+		Protocol: ProtocolVersion,
+		Commands: []ModuleCommand{{Name: []string{"scanner", "collect"}}},
+	}
+}
+
+func TestModule_Describe(t *testing.T) {
+	module := newDescribingModule(t, `{
+		"protocol": 1,
+		"version": "1.2.3",
+		"content_type": "application/vnd.redhat.scanner.collection",
+		"commands": [{"name": ["scanner", "collect"], "flags": [
+			{"name": "profile", "type": "string"},
+			{"name": "tag", "type": "list"},
+			{"name": "fast", "aliases": ["f"], "type": "bool"}
+		]}]
+	}`)
+
+	if err := module.Describe(); err != nil {
+		t.Fatalf("expected 'nil', got '%v'", err)
+	}
+	if module.Version != "1.2.3" {
+		t.Errorf("expected '1.2.3', got '%s'", module.Version)
+	}
+	if module.ArchiveContentType != "application/vnd.redhat.scanner.collection" {
+		t.Errorf("expected content type to be updated, got '%s'", module.ArchiveContentType)
+	}
+
+	valid := [][]string{
+		{},
+		{"--profile", "x"},
+		{"--profile=x", "--tag", "a", "--tag", "b"},
+		{"--fast", "-f"},
+	}
+	for _, options := range valid {
+		if err := module.ValidateOptions([]string{"scanner", "collect"}, options); err != nil {
+			t.Errorf("%v: expected 'nil', got '%v'", options, err)
+		}
+	}
+
+	invalid := [][]string{
+		{"--unknown"},
+		{"--profile"},
+		{"--fast=x"},
+		{"positional"},
+	}
+	for _, options := range invalid {
+		if err := module.ValidateOptions([]string{"scanner", "collect"}, options); err == nil {
+			t.Errorf("%v: expected error, got 'nil'", options)
+		}
+	}
+}
+
+func TestModule_Describe_unsupported(t *testing.T) {
+	tests := map[string]string{
+		"newer protocol":  `{"protocol": 99, "version": "1"}`,
+		"foreign command": `{"protocol": 1, "commands": [{"name": ["advisor", "collect"]}]}`,
+		"malformed":       `{"protocol":`,
+	}
+
+	for name, description := range tests {
+		t.Run(name, func(t *testing.T) {
+			module := newDescribingModule(t, description)
+			if err := module.Describe(); err == nil {
+				t.Errorf("expected error, got 'nil'")
+			}
+		})
+	}
+}
+
+func TestGetModuleByCommand_described(t *testing.T) {
+	directory := t.TempDir()
+	script := testutil.WriteScript(t, filepath.Join(directory, "scanner"), `echo '{"protocol": 1, "version": "1.2.3", "commands": [{"name": ["scanner", "scan"]}]}'`)
+	manifest := "name: scanner\nexec: [" + script + "]\nprotocol: 1\ncommands:\n  - name: [scanner, collect]\n"
+	if err := os.WriteFile(filepath.Join(directory, "scanner.yaml"), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	directories, owner := ModuleDirectories, ModuleDirectoryOwner
+	t.Cleanup(func() {
+		ModuleDirectories, ModuleDirectoryOwner = directories, owner
+		ClearModules()
+	})
+	ModuleDirectoryOwner = os.Geteuid()
+	ModuleDirectories = []string{directory}
+	ClearModules()
+
+	// The command is only known from the description.
+	module, ok := GetModuleByCommand([]string{"scanner", "scan"})
+	if !ok {
+		t.Fatalf("expected command from the description to be found")
+	}
+	if module.Version != "1.2.3" {
+		t.Errorf("expected '1.2.3', got '%s'", module.Version)
+	}
+	// The description is kept in the registry.
+	if registered, _ := GetModule("scanner"); registered.Version != "1.2.3" {
+		t.Errorf("expected described module in the registry, got version '%s'", registered.Version)
+	}
+	if _, ok := GetModuleByCommand([]string{"scanner", "unknown"}); ok {
+		t.Errorf("expected unknown command not to be found")
+	}
+}